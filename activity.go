@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// estrutura cardactivity
+type CardActivity struct {
+	ID        int64     `json:"id"`
+	CardID    int       `json:"card_id"`
+	BoardID   int       `json:"board_id"`
+	ActorID   string    `json:"actor_id"`
+	ActorName string    `json:"actor_name,omitempty"`
+	Action    string    `json:"action"`
+	Field     string    `json:"field,omitempty"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ator das rotinas automaticas (arquivamento por prazo); UUID nulo para caber em colunas uuid
+const systemActorID = "00000000-0000-0000-0000-000000000000"
+
+// gravar atividade do card dentro da transacao do handler
+func (app *App) recordCardActivity(tx pgx.Tx, a CardActivity) error {
+	return insertCardActivity(context.Background(), tx, a)
+}
+
+// gravar atividade do card dentro de uma transacao do PgStore
+func insertCardActivity(ctx context.Context, tx pgx.Tx, a CardActivity) error {
+	query := `INSERT INTO card_activity (card_id, board_id, actor_id, action, field, old_value, new_value)
+	          VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)`
+	_, err := tx.Exec(ctx, query, a.CardID, a.BoardID, a.ActorID, a.Action, a.Field, a.OldValue, a.NewValue)
+	return err
+}
+
+// valor textual para o historico; vazio vira NULL
+func activityValue(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func activityTimeValue(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC().Format(time.RFC3339)
+	return &value
+}
+
+func sameActivityValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// campos alterados de um card, um registro por campo
+func cardChanges(cardID, boardID int, actorID string, oldCard, newCard Card) []CardActivity {
+	changes := []struct {
+		field    string
+		oldValue *string
+		newValue *string
+	}{
+		{"title", activityValue(oldCard.Title), activityValue(newCard.Title)},
+		{"description", activityValue(oldCard.Description), activityValue(newCard.Description)},
+		{"assigned_to", activityValue(oldCard.AssignedTo), activityValue(newCard.AssignedTo)},
+		{"priority", activityValue(oldCard.Priority), activityValue(newCard.Priority)},
+		{"due_date", activityTimeValue(oldCard.DueDate), activityTimeValue(newCard.DueDate)},
+		{"column_id", activityValue(strconv.Itoa(oldCard.ColumnID)), activityValue(strconv.Itoa(newCard.ColumnID))},
+		{"completed_at", activityTimeValue(oldCard.CompletedAt), activityTimeValue(newCard.CompletedAt)},
+	}
+	activity := make([]CardActivity, 0)
+	for _, change := range changes {
+		if sameActivityValue(change.oldValue, change.newValue) {
+			continue
+		}
+		activity = append(activity, CardActivity{
+			CardID:   cardID,
+			BoardID:  boardID,
+			ActorID:  actorID,
+			Action:   "updated",
+			Field:    change.field,
+			OldValue: change.oldValue,
+			NewValue: change.newValue,
+		})
+	}
+	return activity
+}
+
+// endpoint historico do card
+func (app *App) getCardActivity(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do card inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	// o historico sobrevive ao card, entao o board vem do proprio log quando o card ja nao existe
+	boardID, err := app.getBoardIDFromCard(cardID)
+	if err != nil {
+		err = app.db.QueryRow(context.Background(), "SELECT board_id FROM card_activity WHERE card_id = $1 LIMIT 1", cardID).Scan(&boardID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card não encontrado"})
+		}
+	}
+	hasPermission, err := app.checkBoardPermission(userID, boardID)
+	if err != nil || !hasPermission {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
+	}
+
+	query := `SELECT id, card_id, board_id, actor_id, action, COALESCE(field, ''), old_value, new_value, created_at
+	          FROM card_activity WHERE card_id = $1 ORDER BY created_at, id`
+	rows, err := app.db.Query(context.Background(), query, cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar histórico do card %d: %v", cardID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao buscar histórico do card"})
+	}
+	defer rows.Close()
+
+	activity := make([]CardActivity, 0)
+	for rows.Next() {
+		var a CardActivity
+		if err := rows.Scan(&a.ID, &a.CardID, &a.BoardID, &a.ActorID, &a.Action, &a.Field, &a.OldValue, &a.NewValue, &a.CreatedAt); err != nil {
+			log.Printf("Erro ao escanear atividade do card: %v", err)
+			continue
+		}
+		activity = append(activity, a)
+	}
+	rows.Close()
+
+	names := make(map[string]string)
+	for i := range activity {
+		name, ok := names[activity[i].ActorID]
+		if !ok {
+			if activity[i].ActorID == systemActorID {
+				name = "Sistema"
+			} else {
+				name = app.getDisplayName(context.Background(), activity[i].ActorID)
+			}
+			names[activity[i].ActorID] = name
+		}
+		activity[i].ActorName = name
+	}
+	return c.JSON(activity)
+}
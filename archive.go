@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// cards concluidos ha mais que isso sao arquivados automaticamente
+const defaultAutoArchiveDays = 30
+
+// dias configuraveis por CARD_AUTO_ARCHIVE_DAYS; 0 desliga o arquivamento automatico
+func autoArchiveDays() int {
+	if raw := os.Getenv("CARD_AUTO_ARCHIVE_DAYS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			return n
+		}
+		log.Printf("Aviso: CARD_AUTO_ARCHIVE_DAYS inválido (%s), usando %d", raw, defaultAutoArchiveDays)
+	}
+	return defaultAutoArchiveDays
+}
+
+// endpoint arquivar card
+func (app *App) archiveCard(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+
+	// o card sai da coluna e os de baixo sobem
+	var columnID, position int
+	err = tx.QueryRow(context.Background(), `
+		UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW()
+		WHERE id = $1 AND archived_at IS NULL AND deleted_at IS NULL
+		RETURNING column_id, position`, cardID, userID).Scan(&columnID, &position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "O card já está arquivado."})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar o card"})
+	}
+	_, err = tx.Exec(context.Background(),
+		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2 AND deleted_at IS NULL AND archived_at IS NULL",
+		columnID, position)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar a coluna"})
+	}
+	if err := app.recordCardActivity(tx, CardActivity{CardID: cardID, BoardID: boardID, ActorID: userID, Action: "archived"}); err != nil {
+		log.Printf("Erro ao registrar arquivamento do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar o card"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar o arquivamento"})
+	}
+
+	app.broadcast(boardID, WsMessage{
+		Type:     "CARD_ARCHIVED",
+		Payload:  fiber.Map{"card_id": cardID, "column_id": columnID},
+		SenderID: userID,
+	})
+	card, err := app.getCardByID(cardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o card arquivado"})
+	}
+	return c.JSON(card)
+}
+
+// endpoint desarquivar card (volta para o fim da coluna)
+func (app *App) unarchiveCard(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+
+	err = tx.QueryRow(context.Background(), `
+		UPDATE cards SET archived_at = NULL, archived_by = NULL, updated_at = NOW(),
+			position = (SELECT COUNT(*) FROM cards o WHERE o.column_id = cards.column_id AND o.deleted_at IS NULL AND o.archived_at IS NULL)
+		WHERE id = $1 AND archived_at IS NOT NULL AND deleted_at IS NULL
+		RETURNING id`, cardID).Scan(&cardID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "O card não está arquivado."})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desarquivar o card"})
+	}
+	if err := app.recordCardActivity(tx, CardActivity{CardID: cardID, BoardID: boardID, ActorID: userID, Action: "unarchived"}); err != nil {
+		log.Printf("Erro ao registrar desarquivamento do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desarquivar o card"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar o desarquivamento"})
+	}
+
+	card, err := app.getCardByID(cardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o card"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_UNARCHIVED", Payload: card, SenderID: userID})
+	return c.JSON(card)
+}
+
+// endpoint arquivar todos os cards de uma coluna
+func (app *App) archiveColumnCards(c *fiber.Ctx) error {
+	columnID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
+	}
+	boardID, err := app.getBoardIDFromColumn(columnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
+	}
+	userID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+
+	rows, err := tx.Query(context.Background(), `
+		UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW()
+		WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL
+		RETURNING id`, columnID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar os cards da coluna"})
+	}
+	cardIDs := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar os cards da coluna"})
+		}
+		cardIDs = append(cardIDs, id)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar os cards da coluna"})
+	}
+	for _, id := range cardIDs {
+		if err := app.recordCardActivity(tx, CardActivity{CardID: id, BoardID: boardID, ActorID: userID, Action: "archived"}); err != nil {
+			log.Printf("Erro ao registrar arquivamento do card %d: %v", id, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar os cards da coluna"})
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar o arquivamento"})
+	}
+
+	if len(cardIDs) > 0 {
+		app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil, SenderID: userID})
+	}
+	return c.JSON(fiber.Map{"archived": len(cardIDs), "card_ids": cardIDs})
+}
+
+// renumerar os cards ativos das colunas, sem buracos
+func compactCardPositions(ctx context.Context, tx pgx.Tx, columnIDs []int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE cards c SET position = r.rn - 1
+		FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY column_id ORDER BY position, id) AS rn
+		      FROM cards WHERE column_id = ANY($1) AND deleted_at IS NULL AND archived_at IS NULL) r
+		WHERE c.id = r.id AND c.position <> r.rn - 1`, columnIDs)
+	return err
+}
+
+// arquivar os cards concluidos antes de cutoff; devolve quantos por board
+func (app *App) archiveCompletedBefore(ctx context.Context, cutoff time.Time) (map[int]int64, error) {
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH archived AS (
+			UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW()
+			WHERE archived_at IS NULL AND deleted_at IS NULL AND completed_at < $1
+			RETURNING id, column_id
+		)
+		SELECT a.id, a.column_id, col.board_id FROM archived a
+		JOIN columns col ON col.id = a.column_id`, cutoff, systemActorID)
+	if err != nil {
+		return nil, err
+	}
+	archived := make([]CardActivity, 0)
+	seenColumns := make(map[int]bool)
+	columnIDs := make([]int, 0)
+	perBoard := make(map[int]int64)
+	for rows.Next() {
+		var columnID int
+		a := CardActivity{ActorID: systemActorID, Action: "archived"}
+		if err := rows.Scan(&a.CardID, &columnID, &a.BoardID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archived = append(archived, a)
+		if !seenColumns[columnID] {
+			seenColumns[columnID] = true
+			columnIDs = append(columnIDs, columnID)
+		}
+		perBoard[a.BoardID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, a := range archived {
+		if err := app.recordCardActivity(tx, a); err != nil {
+			return nil, err
+		}
+	}
+	if len(columnIDs) == 0 {
+		return perBoard, nil
+	}
+	if err := compactCardPositions(ctx, tx, columnIDs); err != nil {
+		return nil, err
+	}
+	return perBoard, tx.Commit(ctx)
+}
+
+// arquivamento automatico periodico
+func (app *App) autoArchiveCards(ctx context.Context) {
+	days := autoArchiveDays()
+	if days == 0 {
+		log.Println("Arquivamento automático de cards desativado (CARD_AUTO_ARCHIVE_DAYS=0)")
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		perBoard, err := app.archiveCompletedBefore(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Printf("Erro ao arquivar cards concluídos: %v", err)
+		}
+		for boardID, count := range perBoard {
+			log.Printf("Cards arquivados automaticamente no board %d: %d", boardID, count)
+			app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// bucket dos anexos de cards (privado, download passa pela API)
+const attachmentsBucket = "attachments"
+
+// tamanho maximo padrao de um anexo
+const defaultAttachmentMaxBytes = 10 << 20
+
+// tipos aceitos pelo conteudo detectado; imagens vem de imageUploadTypes (SVG fica de fora)
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"text/plain":      true,
+	"application/zip": true,
+}
+
+// container OLE dos formatos antigos do Office (.doc, .xls)
+const oleContentType = "application/x-ole-storage"
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// formatos que o DetectContentType nao distingue: o tipo declarado vale
+// quando o conteudo detectado e o container esperado
+var attachmentContainers = map[string]string{
+	"text/csv":                 "text/plain",
+	"application/msword":       oleContentType,
+	"application/vnd.ms-excel": oleContentType,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "application/zip",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       "application/zip",
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// estrutura attachment
+type Attachment struct {
+	ID          int       `json:"id"`
+	CardID      int       `json:"card_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	storageKey  string
+}
+
+// limite configuravel por ATTACHMENT_MAX_BYTES
+func attachmentMaxBytes() int64 {
+	if raw := os.Getenv("ATTACHMENT_MAX_BYTES"); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("Aviso: ATTACHMENT_MAX_BYTES inválido (%s), usando %d", raw, int64(defaultAttachmentMaxBytes))
+	}
+	return defaultAttachmentMaxBytes
+}
+
+// tipo do anexo pelo conteudo; o Content-Type do cliente so desempata formatos do Office e CSV
+func attachmentContentType(declared string, data []byte) (string, bool) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if sniffed == "application/octet-stream" && bytes.HasPrefix(data, oleMagic) {
+		sniffed = oleContentType
+	}
+	declared, _, _ = mime.ParseMediaType(declared)
+	if container, ok := attachmentContainers[declared]; ok && container == sniffed {
+		return declared, true
+	}
+	if _, ok := imageUploadTypes[sniffed]; ok {
+		return sniffed, true
+	}
+	return sniffed, allowedAttachmentTypes[sniffed]
+}
+
+// nome seguro para a chave no storage
+func sanitizeFileName(name string) string {
+	base := filepath.Base(name)
+	base = unsafeFileNameChars.ReplaceAllString(base, "_")
+	if base == "" || base == "." || base == "_" {
+		return "arquivo"
+	}
+	return base
+}
+
+// endpoint enviar anexo
+func (app *App) uploadCardAttachment(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nenhum arquivo enviado"})
+	}
+	maxBytes := attachmentMaxBytes()
+	if file.Size > maxBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": fmt.Sprintf("Arquivo excede o limite de %d bytes", maxBytes)})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao abrir o arquivo"})
+	}
+	defer src.Close()
+	fileBytes, err := io.ReadAll(io.LimitReader(src, maxBytes+1))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao ler o arquivo"})
+	}
+	if int64(len(fileBytes)) > maxBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": fmt.Sprintf("Arquivo excede o limite de %d bytes", maxBytes)})
+	}
+
+	contentType, ok := attachmentContentType(file.Header.Get("Content-Type"), fileBytes)
+	if !ok {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": fmt.Sprintf("Tipo de arquivo não permitido: %s", contentType)})
+	}
+
+	attachment := Attachment{
+		CardID:      cardID,
+		FileName:    filepath.Base(file.Filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(fileBytes)),
+		UploadedBy:  userID,
+	}
+	attachment.storageKey = fmt.Sprintf("card-%d/%s-%s", cardID, newMessageID(), sanitizeFileName(file.Filename))
+	if _, err := app.storage.Put(context.Background(), attachmentsBucket, attachment.storageKey, contentType, fileBytes); err != nil {
+		log.Printf("❌ Erro ao armazenar anexo do card %d: %v", cardID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Falha ao armazenar o arquivo"})
+	}
+
+	query := `INSERT INTO card_attachments (card_id, file_name, content_type, size_bytes, storage_key, uploaded_by)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err = app.db.QueryRow(context.Background(), query, cardID, attachment.FileName, attachment.ContentType,
+		attachment.SizeBytes, attachment.storageKey, userID).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		log.Printf("Erro ao registrar anexo do card %d: %v", cardID, err)
+		if delErr := app.storage.Delete(context.Background(), attachmentsBucket, attachment.storageKey); delErr != nil {
+			log.Printf("Aviso: anexo órfão no storage (%s): %v", attachment.storageKey, delErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao salvar anexo"})
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "ATTACHMENT_ADDED", Payload: attachment, SenderID: userID})
+	return c.Status(fiber.StatusCreated).JSON(attachment)
+}
+
+// endpoint listar anexos
+func (app *App) getCardAttachments(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	query := `SELECT id, card_id, file_name, content_type, size_bytes, uploaded_by, created_at
+	          FROM card_attachments WHERE card_id = $1 ORDER BY created_at, id`
+	rows, err := app.db.Query(context.Background(), query, cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar anexos do card %d: %v", cardID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao buscar anexos"})
+	}
+	defer rows.Close()
+
+	attachments := make([]Attachment, 0)
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.CardID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.UploadedBy, &a.CreatedAt); err != nil {
+			log.Printf("Erro ao escanear anexo: %v", err)
+			continue
+		}
+		attachments = append(attachments, a)
+	}
+	return c.JSON(attachments)
+}
+
+// buscar anexo de um card
+func (app *App) findAttachment(cardID, attachmentID int) (Attachment, error) {
+	a := Attachment{ID: attachmentID, CardID: cardID}
+	query := `SELECT file_name, content_type, size_bytes, uploaded_by, created_at, storage_key
+	          FROM card_attachments WHERE id = $1 AND card_id = $2`
+	err := app.db.QueryRow(context.Background(), query, attachmentID, cardID).Scan(
+		&a.FileName, &a.ContentType, &a.SizeBytes, &a.UploadedBy, &a.CreatedAt, &a.storageKey)
+	return a, err
+}
+
+// endpoint baixar anexo
+func (app *App) downloadCardAttachment(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	attachmentID, err := strconv.Atoi(c.Params("attachmentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do anexo inválido"})
+	}
+	attachment, err := app.findAttachment(cardID, attachmentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Anexo não encontrado"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao buscar anexo"})
+	}
+	reader, err := app.storage.Get(context.Background(), attachmentsBucket, attachment.storageKey)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Arquivo do anexo não encontrado"})
+		}
+		log.Printf("❌ Erro ao ler anexo %d do storage: %v", attachmentID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao baixar anexo"})
+	}
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	return c.SendStream(reader, int(attachment.SizeBytes))
+}
+
+// endpoint deletar anexo (quem enviou ou o dono do board)
+func (app *App) deleteCardAttachment(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	attachmentID, err := strconv.Atoi(c.Params("attachmentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do anexo inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	attachment, err := app.findAttachment(cardID, attachmentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Anexo não encontrado"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao buscar anexo"})
+	}
+	if attachment.UploadedBy != userID {
+		var ownerID string
+		app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
+		if ownerID != userID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas quem enviou o anexo ou o dono do quadro pode excluí-lo."})
+		}
+	}
+
+	if _, err := app.db.Exec(context.Background(), "DELETE FROM card_attachments WHERE id = $1", attachmentID); err != nil {
+		log.Printf("Erro ao deletar anexo %d: %v", attachmentID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao deletar anexo"})
+	}
+	if err := app.storage.Delete(context.Background(), attachmentsBucket, attachment.storageKey); err != nil {
+		log.Printf("Aviso: anexo %d removido do banco mas não do storage: %v", attachmentID, err)
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "ATTACHMENT_DELETED", Payload: fiber.Map{"card_id": cardID, "attachment_id": attachmentID}, SenderID: userID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BoardRole e o papel do usuario em um board; "" = sem acesso
+type BoardRole string
+
+const (
+	BoardRoleOwner     BoardRole = "owner"
+	BoardRoleEditor    BoardRole = "editor"
+	BoardRoleCommenter BoardRole = "commenter"
+	BoardRoleViewer    BoardRole = "viewer"
+)
+
+// estrutura boardmembership
+type BoardMembership struct {
+	UserID string
+	Role   BoardRole
+}
+
+// BoardCapability e o que uma acao exige do papel
+type BoardCapability int
+
+const (
+	CapView    BoardCapability = iota // ler colunas, cards, comentarios, membros
+	CapComment                        // comentar
+	CapEdit                           // criar/editar/mover/excluir colunas, cards, labels, checklist, anexos
+	CapManage                         // convidar, mudar papeis, remover membros, transferir, excluir o board
+)
+
+var boardRoleCapability = map[BoardRole]BoardCapability{
+	BoardRoleViewer:    CapView,
+	BoardRoleCommenter: CapComment,
+	BoardRoleEditor:    CapEdit,
+	BoardRoleOwner:     CapManage,
+}
+
+func (r BoardRole) can(capability BoardCapability) bool {
+	max, ok := boardRoleCapability[r]
+	return ok && capability <= max
+}
+
+// papeis que podem ser dados a um membro (o dono so muda por transferencia)
+func parseMemberRole(raw string) (BoardRole, bool) {
+	switch role := BoardRole(raw); role {
+	case BoardRoleEditor, BoardRoleCommenter, BoardRoleViewer:
+		return role, true
+	case "":
+		return BoardRoleEditor, true
+	default:
+		return "", false
+	}
+}
+
+// papel efetivo; no board publico todo mundo e pelo menos editor
+func (app *App) boardRole(userID string, boardID int) (BoardRole, error) {
+	board, err := app.boards.GetBoard(context.Background(), boardID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if board.OwnerID == userID {
+		return BoardRoleOwner, nil
+	}
+	role, err := app.boards.BoardMemberRole(context.Background(), boardID, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if board.IsPublic && !role.can(CapEdit) {
+		return BoardRoleEditor, nil
+	}
+	return role, nil
+}
+
+// checar a capacidade e escrever o 403 se faltar
+func (app *App) requireBoardCapability(c *fiber.Ctx, userID string, boardID int, capability BoardCapability) (bool, error) {
+	role, err := app.boardRole(userID, boardID)
+	if err != nil {
+		log.Printf("Erro ao verificar papel no quadro %d: %v", boardID, err)
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar permissões"})
+	}
+	if role == "" {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
+	}
+	if !role.can(capability) {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Seu papel neste quadro não permite esta ação.", "role": role})
+	}
+	return true, nil
+}
+
+// endpoint mudar papel de membro (apenas o dono)
+func (app *App) updateBoardMemberRole(c *fiber.Ctx) error {
+	boardID, err := strconv.Atoi(c.Params("boardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do quadro inválido"})
+	}
+	memberID := c.Params("memberId")
+	userID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapManage); !ok {
+		return resp
+	}
+
+	var payload struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&payload); err != nil || payload.Role == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido: informe role"})
+	}
+	role, ok := parseMemberRole(payload.Role)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Papel inválido. Use editor, commenter ou viewer."})
+	}
+	if memberID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O dono não muda o próprio papel; transfira o quadro."})
+	}
+
+	if err := app.boards.SetBoardMemberRole(context.Background(), boardID, memberID, role); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Membro não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar o papel do membro"})
+	}
+	app.broadcast(boardID, WsMessage{
+		Type:     "MEMBER_ROLE_UPDATED",
+		Payload:  fiber.Map{"user_id": memberID, "role": role},
+		SenderID: userID,
+	})
+	return c.JSON(fiber.Map{"user_id": memberID, "role": role})
+}
+
+// endpoint transferir board (apenas o dono, para um membro)
+func (app *App) transferBoardOwnership(c *fiber.Ctx) error {
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do quadro inválido"})
+	}
+	userID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapManage); !ok {
+		return resp
+	}
+
+	var payload struct {
+		NewOwnerID string `json:"new_owner_id"`
+	}
+	if err := c.BodyParser(&payload); err != nil || payload.NewOwnerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido: informe new_owner_id"})
+	}
+	if payload.NewOwnerID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Você já é o dono deste quadro."})
+	}
+
+	err = app.boards.TransferBoardOwnership(context.Background(), boardID, userID, payload.NewOwnerID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O novo dono precisa ser membro do quadro."})
+		}
+		log.Printf("Erro ao transferir o quadro %d: %v", boardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao transferir o quadro"})
+	}
+	app.broadcast(boardID, WsMessage{
+		Type:     "BOARD_OWNER_CHANGED",
+		Payload:  fiber.Map{"owner_id": payload.NewOwnerID, "previous_owner_id": userID},
+		SenderID: userID,
+	})
+	return c.JSON(fiber.Map{"owner_id": payload.NewOwnerID})
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// coluna inicial de um template de board
+type BoardTemplateColumn struct {
+	Title    string         `json:"title"`
+	Color    string         `json:"color"`
+	Category ColumnCategory `json:"category"`
+	ColumnPolicy
+}
+
+// etiqueta criada junto com o board
+type BoardTemplateLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// card esqueleto; Column e o indice em Columns e Labels sao nomes de etiquetas do template
+type BoardTemplateCard struct {
+	Column      int        `json:"column"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Priority    string     `json:"priority"`
+	AssignedTo  string     `json:"assigned_to,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Labels      []string   `json:"labels"`
+	Checklist   []string   `json:"checklist"`
+}
+
+// o que um template (ou uma copia) cria no board novo
+type BoardStructure struct {
+	Columns []BoardTemplateColumn `json:"columns"`
+	Labels  []BoardTemplateLabel  `json:"labels"`
+	Cards   []BoardTemplateCard   `json:"cards"`
+}
+
+// estrutura boardtemplate; os embutidos tem Key, os salvos tem ID
+type BoardTemplate struct {
+	ID          int        `json:"id,omitempty"`
+	Key         string     `json:"key,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	OwnerID     string     `json:"owner_id,omitempty"`
+	IsShared    bool       `json:"is_shared"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	BoardStructure
+}
+
+// templates que acompanham o sistema
+const (
+	TemplateKanban      = "kanban"
+	TemplateAtendimento = "atendimento"
+)
+
+var builtinBoardTemplates = []BoardTemplate{
+	{
+		Key:      TemplateKanban,
+		Name:     "Kanban",
+		IsShared: true,
+		BoardStructure: BoardStructure{Columns: []BoardTemplateColumn{
+			{Title: "A Fazer", Color: "#58a6ff", Category: CategoryBacklog},
+			{Title: "Em Andamento", Color: "#d29922", Category: CategoryInProgress},
+			{Title: "Concluído", Color: "#3fb950", Category: CategoryDoneSuccess},
+		}},
+	},
+	{
+		Key:      TemplateAtendimento,
+		Name:     "Atendimento",
+		IsShared: true,
+		BoardStructure: BoardStructure{Columns: []BoardTemplateColumn{
+			{Title: "A Fazer", Color: "#58a6ff", Category: CategoryBacklog},
+			{Title: "Em Andamento", Color: "#d29922", Category: CategoryInProgress},
+			{Title: "Solucionado", Color: "#3fb950", Category: CategoryDoneSuccess},
+			{Title: "Não Solucionado", Color: "#f85149", Category: CategoryDoneFailure},
+		}},
+	},
+}
+
+func builtinBoardTemplate(key string) (BoardTemplate, bool) {
+	for _, tpl := range builtinBoardTemplates {
+		if tpl.Key == key {
+			return tpl, true
+		}
+	}
+	return BoardTemplate{}, false
+}
+
+// template usado quando o board e criado sem escolher um
+func defaultBoardTemplate(isPublic bool) BoardTemplate {
+	key := TemplateKanban
+	if isPublic {
+		key = TemplateAtendimento
+	}
+	tpl, _ := builtinBoardTemplate(key)
+	return tpl
+}
+
+// validar e normalizar a estrutura; devolve a mensagem para o 400
+func (s *BoardStructure) validate() string {
+	if len(s.Columns) == 0 {
+		return "O template precisa de pelo menos uma coluna"
+	}
+	for i := range s.Columns {
+		col := &s.Columns[i]
+		if col.Title == "" {
+			return "Toda coluna do template precisa de título"
+		}
+		category, ok := parseColumnCategory(string(col.Category))
+		if !ok {
+			return fmt.Sprintf("Categoria inválida na coluna '%s'", col.Title)
+		}
+		col.Category = category
+		if col.WipLimit != nil && *col.WipLimit <= 0 {
+			return fmt.Sprintf("wip_limit inválido na coluna '%s'", col.Title)
+		}
+	}
+	labels := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, l := range s.Labels {
+		if l.Name == "" {
+			return "Toda etiqueta do template precisa de nome"
+		}
+		// o board nao aceita duas etiquetas com o mesmo nome (sem diferenciar maiusculas)
+		if seen[strings.ToLower(l.Name)] {
+			return fmt.Sprintf("Etiqueta '%s' repetida no template", l.Name)
+		}
+		seen[strings.ToLower(l.Name)] = true
+		labels[l.Name] = true
+	}
+	for _, card := range s.Cards {
+		if card.Title == "" {
+			return "Todo card do template precisa de título"
+		}
+		if card.Column < 0 || card.Column >= len(s.Columns) {
+			return fmt.Sprintf("O card '%s' aponta para uma coluna inexistente", card.Title)
+		}
+		for _, name := range card.Labels {
+			if !labels[name] {
+				return fmt.Sprintf("O card '%s' usa a etiqueta '%s', que não está no template", card.Title, name)
+			}
+		}
+	}
+	return ""
+}
+
+// criar colunas, etiquetas e cards da estrutura no board, na ordem
+func (app *App) applyBoardStructure(ctx context.Context, tx pgx.Tx, boardID int, actorID string, s BoardStructure) error {
+	columnIDs := make([]int, len(s.Columns))
+	for i, col := range s.Columns {
+		color := col.Color
+		if color == "" {
+			color = "#e4e6ea"
+		}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO columns (board_id, title, position, color, category, wip_limit, wip_soft, require_assignee, require_due_date)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			boardID, col.Title, i, color, col.Category, col.WipLimit, col.WipSoft, col.RequireAssignee, col.RequireDueDate,
+		).Scan(&columnIDs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	labelIDs := make(map[string]int, len(s.Labels))
+	for _, l := range s.Labels {
+		color := l.Color
+		if color == "" {
+			color = "#8b949e"
+		}
+		var id int
+		if err := tx.QueryRow(ctx,
+			"INSERT INTO board_labels (board_id, name, color) VALUES ($1, $2, $3) RETURNING id",
+			boardID, l.Name, color).Scan(&id); err != nil {
+			return err
+		}
+		labelIDs[l.Name] = id
+	}
+
+	positions := make([]int, len(s.Columns))
+	for _, card := range s.Cards {
+		priority := card.Priority
+		if priority == "" {
+			priority = "media"
+		}
+		var cardID int
+		err := tx.QueryRow(ctx, `
+			INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position, completed_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, CASE WHEN $8 THEN NOW() END) RETURNING id`,
+			columnIDs[card.Column], card.Title, card.Description, card.AssignedTo, priority, card.DueDate,
+			positions[card.Column], s.Columns[card.Column].Category.done(),
+		).Scan(&cardID)
+		if err != nil {
+			return err
+		}
+		positions[card.Column]++
+		for _, name := range card.Labels {
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO card_labels (card_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", cardID, labelIDs[name]); err != nil {
+				return err
+			}
+		}
+		for i, text := range card.Checklist {
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO card_checklist_items (card_id, text, position) VALUES ($1, $2, $3)", cardID, text, i); err != nil {
+				return err
+			}
+		}
+		if err := app.recordCardActivity(tx, CardActivity{CardID: cardID, BoardID: boardID, ActorID: actorID, Action: "created", NewValue: activityValue(card.Title)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ler a estrutura de um board existente. Sem includeCards so colunas e etiquetas;
+// keepDetails mantem responsavel e prazo dos cards (copia de board, nao template)
+func (app *App) captureBoardStructure(ctx context.Context, boardID int, includeCards, keepDetails bool) (BoardStructure, error) {
+	s := BoardStructure{
+		Columns: make([]BoardTemplateColumn, 0),
+		Labels:  make([]BoardTemplateLabel, 0),
+		Cards:   make([]BoardTemplateCard, 0),
+	}
+	rows, err := app.db.Query(ctx, `SELECT `+columnSelectColumns+`
+		FROM columns WHERE board_id = $1 AND deleted_at IS NULL ORDER BY position`, boardID)
+	if err != nil {
+		return s, err
+	}
+	columnIndex := make(map[int]int)
+	for rows.Next() {
+		var col Column
+		if err := scanColumn(rows, &col); err != nil {
+			rows.Close()
+			return s, err
+		}
+		columnIndex[col.ID] = len(s.Columns)
+		s.Columns = append(s.Columns, BoardTemplateColumn{Title: col.Title, Color: col.Color, Category: col.Category, ColumnPolicy: col.ColumnPolicy})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	rows, err = app.db.Query(ctx, "SELECT name, color FROM board_labels WHERE board_id = $1 ORDER BY name", boardID)
+	if err != nil {
+		return s, err
+	}
+	for rows.Next() {
+		var l BoardTemplateLabel
+		if err := rows.Scan(&l.Name, &l.Color); err != nil {
+			rows.Close()
+			return s, err
+		}
+		s.Labels = append(s.Labels, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+	if !includeCards {
+		return s, nil
+	}
+
+	// cards ativos com etiquetas e checklist (itens voltam desmarcados)
+	rows, err = app.db.Query(ctx, `
+		SELECT c.column_id, c.title, COALESCE(c.description, ''), COALESCE(c.priority, 'media'), COALESCE(c.assigned_to, ''), c.due_date,
+			COALESCE((SELECT array_agg(l.name ORDER BY l.name) FROM card_labels cl JOIN board_labels l ON l.id = cl.label_id WHERE cl.card_id = c.id), '{}'),
+			COALESCE((SELECT array_agg(i.text ORDER BY i.position) FROM card_checklist_items i WHERE i.card_id = c.id), '{}')
+		FROM cards c JOIN columns col ON col.id = c.column_id
+		WHERE col.board_id = $1 AND col.deleted_at IS NULL AND c.deleted_at IS NULL AND c.archived_at IS NULL
+		ORDER BY col.position, c.position`, boardID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var columnID int
+		var card BoardTemplateCard
+		if err := rows.Scan(&columnID, &card.Title, &card.Description, &card.Priority, &card.AssignedTo, &card.DueDate,
+			&card.Labels, &card.Checklist); err != nil {
+			return s, err
+		}
+		card.Column = columnIndex[columnID]
+		if !keepDetails {
+			card.AssignedTo = ""
+			card.DueDate = nil
+		}
+		s.Cards = append(s.Cards, card)
+	}
+	return s, rows.Err()
+}
+
+// template salvo que o usuario pode ver (dele ou compartilhado)
+func (app *App) savedBoardTemplate(ctx context.Context, templateID int, userID string) (BoardTemplate, error) {
+	var tpl BoardTemplate
+	var definition []byte
+	var createdAt time.Time
+	err := app.db.QueryRow(ctx, `
+		SELECT id, name, description, owner_id, is_shared, definition, created_at
+		FROM board_templates WHERE id = $1 AND (owner_id = $2 OR is_shared)`, templateID, userID).Scan(
+		&tpl.ID, &tpl.Name, &tpl.Description, &tpl.OwnerID, &tpl.IsShared, &definition, &createdAt)
+	if err != nil {
+		return tpl, notFound(err)
+	}
+	tpl.CreatedAt = &createdAt
+	return tpl, json.Unmarshal(definition, &tpl.BoardStructure)
+}
+
+// endpoint listar templates de board (embutidos, do usuario e compartilhados)
+func (app *App) getBoardTemplates(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	templates := append(make([]BoardTemplate, 0, len(builtinBoardTemplates)), builtinBoardTemplates...)
+
+	rows, err := app.db.Query(context.Background(), `
+		SELECT id, name, description, owner_id, is_shared, definition, created_at
+		FROM board_templates WHERE owner_id = $1 OR is_shared ORDER BY name, id`, userID)
+	if err != nil {
+		log.Printf("Erro ao buscar templates de quadro: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar templates"})
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tpl BoardTemplate
+		var definition []byte
+		var createdAt time.Time
+		if err := rows.Scan(&tpl.ID, &tpl.Name, &tpl.Description, &tpl.OwnerID, &tpl.IsShared, &definition, &createdAt); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler template"})
+		}
+		if err := json.Unmarshal(definition, &tpl.BoardStructure); err != nil {
+			log.Printf("Template de quadro %d com definição inválida: %v", tpl.ID, err)
+			continue
+		}
+		tpl.CreatedAt = &createdAt
+		templates = append(templates, tpl)
+	}
+	return c.JSON(templates)
+}
+
+// endpoint salvar template: a partir de um board (board_id) ou com columns/labels/cards no corpo
+func (app *App) createBoardTemplate(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		IsShared     bool   `json:"is_shared"`
+		BoardID      int    `json:"board_id"`
+		IncludeCards bool   `json:"include_cards"`
+		BoardStructure
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	if payload.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "O nome do template é obrigatório"})
+	}
+
+	structure := payload.BoardStructure
+	if payload.BoardID != 0 {
+		if ok, resp := app.requireBoardCapability(c, userID, payload.BoardID, CapView); !ok {
+			return resp
+		}
+		var err error
+		structure, err = app.captureBoardStructure(context.Background(), payload.BoardID, payload.IncludeCards, false)
+		if err != nil {
+			log.Printf("Erro ao ler a estrutura do quadro %d: %v", payload.BoardID, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler a estrutura do quadro"})
+		}
+	}
+	if msg := structure.validate(); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	definition, err := json.Marshal(structure)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar o template"})
+	}
+
+	tpl := BoardTemplate{Name: payload.Name, Description: payload.Description, OwnerID: userID, IsShared: payload.IsShared, BoardStructure: structure}
+	var createdAt time.Time
+	err = app.db.QueryRow(context.Background(), `
+		INSERT INTO board_templates (name, description, owner_id, is_shared, definition)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		tpl.Name, tpl.Description, userID, tpl.IsShared, definition).Scan(&tpl.ID, &createdAt)
+	if err != nil {
+		log.Printf("Erro ao salvar template de quadro: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar o template"})
+	}
+	tpl.CreatedAt = &createdAt
+	return c.Status(201).JSON(tpl)
+}
+
+// endpoint duplicar board: estrutura sempre, cards com include_cards. Quem nao gerencia o board
+// so faz copia privada e sem responsaveis/prazos dos cards
+func (app *App) duplicateBoard(c *fiber.Ctx) error {
+	sourceID, ok, resp := app.boardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+	role, err := app.boardRole(userID, sourceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar permissões"})
+	}
+	canManage := role.can(CapManage)
+	var payload struct {
+		Title        string `json:"title"`
+		IncludeCards bool   `json:"include_cards"`
+		IsPublic     *bool  `json:"is_public"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+
+	source, err := app.boards.GetBoard(context.Background(), sourceID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Quadro não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o quadro"})
+	}
+	if payload.IsPublic != nil && *payload.IsPublic && !canManage {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Só o dono do quadro pode criar uma cópia pública."})
+	}
+	structure, err := app.captureBoardStructure(context.Background(), sourceID, payload.IncludeCards, canManage)
+	if err != nil {
+		log.Printf("Erro ao ler a estrutura do quadro %d: %v", sourceID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler a estrutura do quadro"})
+	}
+
+	board := Board{
+		Title:       payload.Title,
+		Description: source.Description,
+		Color:       source.Color,
+		IsPublic:    source.IsPublic && canManage,
+		OwnerID:     userID,
+	}
+	if board.Title == "" {
+		board.Title = source.Title + " (cópia)"
+	}
+	if payload.IsPublic != nil {
+		board.IsPublic = *payload.IsPublic
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+	err = tx.QueryRow(context.Background(), `
+		INSERT INTO boards (title, description, owner_id, is_public, color)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		board.Title, board.Description, userID, board.IsPublic, board.Color).Scan(&board.ID, &board.CreatedAt, &board.UpdatedAt)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar a cópia do quadro"})
+	}
+	if err := app.applyBoardStructure(context.Background(), tx, board.ID, userID, structure); err != nil {
+		log.Printf("Erro ao copiar o quadro %d para %d: %v", sourceID, board.ID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao copiar o quadro"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a cópia do quadro"})
+	}
+	return c.Status(201).JSON(board)
+}
+
+// template pedido na criacao do board: template_id (salvo) ou template (embutido)
+func (app *App) boardTemplateForCreate(c *fiber.Ctx, userID string, templateID int, key string, isPublic bool) (BoardTemplate, bool, error) {
+	switch {
+	case templateID != 0:
+		tpl, err := app.savedBoardTemplate(context.Background(), templateID, userID)
+		if errors.Is(err, ErrNotFound) {
+			return tpl, false, c.Status(400).JSON(fiber.Map{"error": "Template " + strconv.Itoa(templateID) + " não encontrado"})
+		}
+		if err != nil {
+			log.Printf("Erro ao carregar o template %d: %v", templateID, err)
+			return tpl, false, c.Status(500).JSON(fiber.Map{"error": "Erro ao carregar o template"})
+		}
+		return tpl, true, nil
+	case key != "":
+		tpl, ok := builtinBoardTemplate(key)
+		if !ok {
+			return tpl, false, c.Status(400).JSON(fiber.Map{"error": "Template de quadro desconhecido"})
+		}
+		return tpl, true, nil
+	default:
+		return defaultBoardTemplate(isPublic), true, nil
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// estrutura checklistitem
+type ChecklistItem struct {
+	ID        int        `json:"id"`
+	CardID    int        `json:"card_id"`
+	Text      string     `json:"text"`
+	Position  int        `json:"position"`
+	Done      bool       `json:"done"`
+	DoneBy    *string    `json:"done_by,omitempty"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// preencher os campos de checklist do card
+func applyChecklistCounts(card *Card, counts [2]int) {
+	total, done := counts[0], counts[1]
+	if total == 0 {
+		return
+	}
+	completion := done * 100 / total
+	card.ChecklistTotal = total
+	card.ChecklistDone = done
+	card.ChecklistCompletion = &completion
+}
+
+// reenviar o card inteiro apos mudanca no checklist
+func (app *App) broadcastCardUpdated(boardID, cardID int, senderID string) {
+	card, err := app.getCardByID(cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
+		return
+	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_UPDATED", Payload: card, SenderID: senderID})
+}
+
+// endpoint listar checklist
+func (app *App) getCardChecklist(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	rows, err := app.db.Query(context.Background(), `
+		SELECT id, card_id, text, position, done, done_by::text, done_at, created_at
+		FROM card_checklist_items WHERE card_id = $1 ORDER BY position, id`, cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar checklist do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar checklist"})
+	}
+	defer rows.Close()
+	items := make([]ChecklistItem, 0)
+	for rows.Next() {
+		var item ChecklistItem
+		if err := rows.Scan(&item.ID, &item.CardID, &item.Text, &item.Position, &item.Done, &item.DoneBy, &item.DoneAt, &item.CreatedAt); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler item do checklist"})
+		}
+		items = append(items, item)
+	}
+	return c.JSON(items)
+}
+
+// endpoint adicionar item
+func (app *App) addChecklistItem(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O texto do item é obrigatório"})
+	}
+
+	item := ChecklistItem{CardID: cardID, Text: payload.Text}
+	err := app.db.QueryRow(context.Background(), `
+		INSERT INTO card_checklist_items (card_id, text, position)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(position), -1) + 1 FROM card_checklist_items WHERE card_id = $1))
+		RETURNING id, position, created_at`, cardID, payload.Text).Scan(&item.ID, &item.Position, &item.CreatedAt)
+	if err != nil {
+		log.Printf("Erro ao adicionar item ao checklist do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao adicionar item"})
+	}
+	app.broadcastCardUpdated(boardID, cardID, userID)
+	return c.Status(201).JSON(item)
+}
+
+// endpoint marcar/desmarcar item
+func (app *App) toggleChecklistItem(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	itemID, err := strconv.Atoi(c.Params("itemId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do item inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	var item ChecklistItem
+	err = app.db.QueryRow(context.Background(), `
+		UPDATE card_checklist_items SET
+			done = NOT done,
+			done_by = CASE WHEN done THEN NULL ELSE $3::uuid END,
+			done_at = CASE WHEN done THEN NULL ELSE NOW() END
+		WHERE id = $1 AND card_id = $2
+		RETURNING id, card_id, text, position, done, done_by::text, done_at, created_at`, itemID, cardID, userID).Scan(
+		&item.ID, &item.CardID, &item.Text, &item.Position, &item.Done, &item.DoneBy, &item.DoneAt, &item.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Item não encontrado"})
+		}
+		log.Printf("Erro ao alternar item %d do checklist: %v", itemID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar item"})
+	}
+	app.broadcastCardUpdated(boardID, cardID, userID)
+	return c.JSON(item)
+}
+
+// endpoint reordenar checklist
+func (app *App) reorderChecklist(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		OrderedItemIDs []int `json:"ordered_item_ids"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+	for i, itemID := range payload.OrderedItemIDs {
+		if _, err := tx.Exec(context.Background(), "UPDATE card_checklist_items SET position = $1 WHERE id = $2 AND card_id = $3", i, itemID, cardID); err != nil {
+			log.Printf("Erro ao reordenar item %d do checklist: %v", itemID, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar checklist"})
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a reordenação"})
+	}
+	app.broadcastCardUpdated(boardID, cardID, userID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// endpoint deletar item
+func (app *App) deleteChecklistItem(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	itemID, err := strconv.Atoi(c.Params("itemId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do item inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+	var position int
+	err = tx.QueryRow(context.Background(), "DELETE FROM card_checklist_items WHERE id = $1 AND card_id = $2 RETURNING position", itemID, cardID).Scan(&position)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Item não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar item"})
+	}
+	if _, err := tx.Exec(context.Background(), "UPDATE card_checklist_items SET position = position - 1 WHERE card_id = $1 AND position > $2", cardID, position); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar checklist"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a exclusão"})
+	}
+	app.broadcastCardUpdated(boardID, cardID, userID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ColumnPolicy sao as regras para um card entrar na coluna
+type ColumnPolicy struct {
+	WipLimit        *int `json:"wip_limit"`
+	WipSoft         bool `json:"wip_soft"`
+	RequireAssignee bool `json:"require_assignee"`
+	RequireDueDate  bool `json:"require_due_date"`
+}
+
+// ColumnCategory e o papel da coluna no fluxo; define quando um card esta concluido
+type ColumnCategory string
+
+const (
+	CategoryBacklog     ColumnCategory = "backlog"
+	CategoryInProgress  ColumnCategory = "in_progress"
+	CategoryDoneSuccess ColumnCategory = "done_success"
+	CategoryDoneFailure ColumnCategory = "done_failure"
+)
+
+// card que entra numa coluna "done" fica concluido; sair dela reabre
+func (cat ColumnCategory) done() bool {
+	return cat == CategoryDoneSuccess || cat == CategoryDoneFailure
+}
+
+// "" = em andamento
+func parseColumnCategory(raw string) (ColumnCategory, bool) {
+	switch cat := ColumnCategory(raw); cat {
+	case CategoryBacklog, CategoryInProgress, CategoryDoneSuccess, CategoryDoneFailure:
+		return cat, true
+	case "":
+		return CategoryInProgress, true
+	default:
+		return "", false
+	}
+}
+
+// regras que podem barrar a entrada
+const (
+	RuleWipLimit        = "wip_limit"
+	RuleRequireAssignee = "require_assignee"
+	RuleRequireDueDate  = "require_due_date"
+)
+
+// corpo do 409 quando um card nao pode entrar na coluna
+type ColumnPolicyViolation struct {
+	Message   string `json:"error"`
+	Code      string `json:"code"`
+	Rule      string `json:"rule"`
+	ColumnID  int    `json:"column_id"`
+	WipLimit  *int   `json:"wip_limit,omitempty"`
+	CardCount *int   `json:"card_count,omitempty"`
+}
+
+// a violacao volta dos stores como erro
+func (v *ColumnPolicyViolation) Error() string {
+	return v.Message
+}
+
+// limite suave estourado: o card entra, mas o board e avisado
+type WipExceeded struct {
+	ColumnID  int `json:"column_id"`
+	CardID    int `json:"card_id,omitempty"`
+	WipLimit  int `json:"wip_limit"`
+	CardCount int `json:"card_count"`
+}
+
+// checar a entrada de um card na coluna dentro da transacao; trava a coluna para a contagem do WIP
+// nao correr com outra entrada. card.ID = 0 para um card novo
+func checkColumnEntry(ctx context.Context, tx pgx.Tx, columnID int, card Card) (*ColumnPolicyViolation, *WipExceeded, error) {
+	var policy ColumnPolicy
+	err := tx.QueryRow(ctx, `
+		SELECT wip_limit, wip_soft, require_assignee, require_due_date
+		FROM columns WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, columnID).Scan(
+		&policy.WipLimit, &policy.WipSoft, &policy.RequireAssignee, &policy.RequireDueDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var count int
+	if policy.WipLimit != nil {
+		err = tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM cards
+			WHERE column_id = $1 AND id <> $2 AND deleted_at IS NULL AND archived_at IS NULL`, columnID, card.ID).Scan(&count)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	violation, wipExceeded := evaluateColumnEntry(columnID, policy, card, count)
+	return violation, wipExceeded, nil
+}
+
+// aplicar a politica a um card que entra; others = cards ativos que ja estao na coluna
+func evaluateColumnEntry(columnID int, policy ColumnPolicy, card Card, others int) (*ColumnPolicyViolation, *WipExceeded) {
+	violation := func(rule, message string) *ColumnPolicyViolation {
+		return &ColumnPolicyViolation{Message: message, Code: "COLUMN_POLICY_VIOLATION", Rule: rule, ColumnID: columnID}
+	}
+	if policy.RequireAssignee && card.AssignedTo == "" {
+		return violation(RuleRequireAssignee, "Cards nesta coluna precisam de um responsável."), nil
+	}
+	if policy.RequireDueDate && card.DueDate == nil {
+		return violation(RuleRequireDueDate, "Cards nesta coluna precisam de uma data de entrega."), nil
+	}
+	if policy.WipLimit == nil {
+		return nil, nil
+	}
+
+	count := others + 1
+	if count <= *policy.WipLimit {
+		return nil, nil
+	}
+	if policy.WipSoft {
+		return nil, &WipExceeded{ColumnID: columnID, CardID: card.ID, WipLimit: *policy.WipLimit, CardCount: count}
+	}
+	v := violation(RuleWipLimit, fmt.Sprintf("A coluna atingiu o limite de %d cards em andamento.", *policy.WipLimit))
+	v.WipLimit = policy.WipLimit
+	v.CardCount = &others
+	return v, nil
+}
+
+// endpoint definir politica da coluna (apenas o dono do board)
+func (app *App) setColumnPolicy(c *fiber.Ctx) error {
+	columnID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
+	}
+	boardID, err := app.getBoardIDFromColumn(columnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
+	}
+	userID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapManage); !ok {
+		return resp
+	}
+
+	var policy ColumnPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	if policy.WipLimit != nil && *policy.WipLimit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "wip_limit deve ser maior que zero (ou null para remover)"})
+	}
+
+	col, err := app.columns.SetColumnPolicy(context.Background(), columnID, policy)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
+		}
+		log.Printf("Erro ao salvar a política da coluna %d: %v", columnID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar a política da coluna"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "COLUMN_UPDATED", Payload: col, SenderID: userID})
+	return c.JSON(col)
+}
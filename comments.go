@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// estrutura comment, nomes dos campos seguem o tipo Comment do frontend
+type Comment struct {
+	ID        int       `json:"id"`
+	CardID    int       `json:"card_id"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	AuthorID  string    `json:"authorId"`
+	Timestamp time.Time `json:"timestamp"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Edited    bool      `json:"edited"`
+}
+
+// @username ou @email; o @ precisa vir no inicio ou depois de algo que nao seja parte de nome,
+// para o dominio de um email no texto (joao@empresa.com) nao virar mencao
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.\-@])@([\p{L}\p{N}_.\-]+(?:@[\p{L}\p{N}_\-]+(?:\.[\p{L}\p{N}_\-]+)+)?)`)
+
+// extrair mencoes unicas do texto
+func parseMentions(text string) []string {
+	seen := make(map[string]bool)
+	mentions := make([]string, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[strings.ToLower(username)] {
+			continue
+		}
+		seen[strings.ToLower(username)] = true
+		mentions = append(mentions, username)
+	}
+	return mentions
+}
+
+// card e board de uma rota /cards/:id, ja checando o papel do usuario;
+// com ok == false a resposta de erro ja foi escrita e deve ser retornada
+func (app *App) cardBoardForRequest(c *fiber.Ctx, capability BoardCapability) (cardID, boardID int, ok bool, resp error) {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, 0, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do card inválido"})
+	}
+	boardID, err = app.getBoardIDFromCard(cardID)
+	if err != nil {
+		return 0, 0, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card não encontrado"})
+	}
+	if ok, resp := app.requireBoardCapability(c, c.Locals("userID").(string), boardID, capability); !ok {
+		return 0, 0, false, resp
+	}
+	return cardID, boardID, true, nil
+}
+
+// notificar usuarios mencionados que tem acesso ao board
+func (app *App) notifyMentions(tx pgx.Tx, text string, authorID string, boardID, cardID int) {
+	mentions := parseMentions(text)
+	if len(mentions) == 0 {
+		return
+	}
+	var cardTitle string
+	tx.QueryRow(context.Background(), "SELECT title FROM cards WHERE id = $1", cardID).Scan(&cardTitle)
+	authorName := app.getDisplayName(context.Background(), authorID)
+	for _, username := range mentions {
+		mentionedID, err := app.getUserIDByUsername(username)
+		if err != nil || mentionedID == authorID {
+			continue
+		}
+		hasPermission, err := app.checkBoardPermission(mentionedID, boardID)
+		if err != nil || !hasPermission {
+			continue
+		}
+		notification := Notification{
+			UserID:         mentionedID,
+			Type:           "mention",
+			Message:        fmt.Sprintf("%s mencionou você na tarefa: %s", authorName, cardTitle),
+			RelatedBoardID: &boardID,
+			RelatedCardID:  &cardID,
+		}
+		// savepoint: uma notificacao com erro nao pode abortar a transacao do comentario
+		sp, err := tx.Begin(context.Background())
+		if err != nil {
+			log.Printf("Erro ao abrir savepoint da menção para %s: %v", mentionedID, err)
+			continue
+		}
+		if err := app.createNotification(sp, notification); err != nil {
+			log.Printf("Erro ao criar notificação de menção para %s: %v", mentionedID, err)
+			sp.Rollback(context.Background())
+			continue
+		}
+		if err := sp.Commit(context.Background()); err != nil {
+			log.Printf("Erro ao confirmar notificação de menção para %s: %v", mentionedID, err)
+		}
+	}
+}
+
+// endpoint listar comentarios
+func (app *App) getCardComments(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	query := `SELECT id, card_id, text, author_id, created_at, updated_at, edited
+	          FROM card_comments WHERE card_id = $1 ORDER BY created_at, id`
+	rows, err := app.db.Query(context.Background(), query, cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar comentários do card %d: %v", cardID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao buscar comentários"})
+	}
+	defer rows.Close()
+
+	comments := make([]Comment, 0)
+	for rows.Next() {
+		var cm Comment
+		if err := rows.Scan(&cm.ID, &cm.CardID, &cm.Text, &cm.AuthorID, &cm.Timestamp, &cm.UpdatedAt, &cm.Edited); err != nil {
+			log.Printf("Erro ao escanear comentário: %v", err)
+			continue
+		}
+		comments = append(comments, cm)
+	}
+	rows.Close()
+
+	names := make(map[string]string)
+	for i := range comments {
+		name, ok := names[comments[i].AuthorID]
+		if !ok {
+			name = app.getDisplayName(context.Background(), comments[i].AuthorID)
+			names[comments[i].AuthorID] = name
+		}
+		comments[i].Author = name
+	}
+	return c.JSON(comments)
+}
+
+// endpoint criar comentario
+func (app *App) createCardComment(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapComment)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O comentário não pode ser vazio"})
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+
+	comment := Comment{CardID: cardID, Text: payload.Text, AuthorID: userID}
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO card_comments (card_id, author_id, text) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`,
+		cardID, userID, payload.Text).Scan(&comment.ID, &comment.Timestamp, &comment.UpdatedAt)
+	if err != nil {
+		log.Printf("Erro ao criar comentário no card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar comentário"})
+	}
+	comment.Author = app.getDisplayName(context.Background(), userID)
+	app.notifyMentions(tx, payload.Text, userID, boardID, cardID)
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar comentário"})
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "COMMENT_ADDED", Payload: comment, SenderID: userID})
+	return c.Status(201).JSON(comment)
+}
+
+// endpoint editar comentario (apenas o autor)
+func (app *App) updateCardComment(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapComment)
+	if !ok {
+		return resp
+	}
+	commentID, err := strconv.Atoi(c.Params("commentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do comentário inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O comentário não pode ser vazio"})
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+
+	var authorID string
+	err = tx.QueryRow(context.Background(), "SELECT author_id FROM card_comments WHERE id = $1 AND card_id = $2 FOR UPDATE", commentID, cardID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comentário não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentário"})
+	}
+	if authorID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas o autor pode editar este comentário."})
+	}
+
+	comment := Comment{ID: commentID, CardID: cardID, Text: payload.Text, AuthorID: userID, Edited: true}
+	err = tx.QueryRow(context.Background(),
+		`UPDATE card_comments SET text = $1, edited = true, updated_at = NOW() WHERE id = $2 RETURNING created_at, updated_at`,
+		payload.Text, commentID).Scan(&comment.Timestamp, &comment.UpdatedAt)
+	if err != nil {
+		log.Printf("Erro ao atualizar comentário %d: %v", commentID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar comentário"})
+	}
+	comment.Author = app.getDisplayName(context.Background(), userID)
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar atualização"})
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "COMMENT_UPDATED", Payload: comment, SenderID: userID})
+	return c.JSON(comment)
+}
+
+// endpoint deletar comentario (apenas o autor)
+func (app *App) deleteCardComment(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapComment)
+	if !ok {
+		return resp
+	}
+	commentID, err := strconv.Atoi(c.Params("commentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do comentário inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	var authorID string
+	err = app.db.QueryRow(context.Background(), "SELECT author_id FROM card_comments WHERE id = $1 AND card_id = $2", commentID, cardID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comentário não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentário"})
+	}
+	if authorID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas o autor pode excluir este comentário."})
+	}
+
+	_, err = app.db.Exec(context.Background(), "DELETE FROM card_comments WHERE id = $1 AND author_id = $2", commentID, userID)
+	if err != nil {
+		log.Printf("Erro ao deletar comentário %d: %v", commentID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar comentário"})
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "COMMENT_DELETED", Payload: fiber.Map{"card_id": cardID, "comment_id": commentID}, SenderID: userID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
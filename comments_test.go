@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"@ana veja isso", []string{"ana"}},
+		{"oi @ana e @Bia.", []string{"ana", "Bia"}},
+		{"(@ana),@bia", []string{"ana", "bia"}},
+		{"fale com @joao@empresa.com.br", []string{"joao@empresa.com.br"}},
+		{"mande para joao@empresa.com", []string{}},
+		{"@ana @ANA", []string{"ana"}},
+		{"email a@b.c e @@x", []string{}},
+	}
+	for _, tc := range cases {
+		if got := parseMentions(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: esperado %v, veio %v", tc.text, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// cards que vencem dentro dessa janela recebem o lembrete due_soon
+const defaultDueSoonWindow = 24 * time.Hour
+
+// intervalo entre as varreduras de prazos
+const defaultDueReminderInterval = 15 * time.Minute
+
+// tipos de lembrete (tambem sao o type da notificacao)
+const (
+	ReminderDueSoon = "due_soon"
+	ReminderOverdue = "overdue"
+)
+
+// janela configuravel por DUE_SOON_WINDOW (ex: 24h, 48h)
+func dueSoonWindow() time.Duration {
+	if raw := os.Getenv("DUE_SOON_WINDOW"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Aviso: DUE_SOON_WINDOW inválido (%s), usando %s", raw, defaultDueSoonWindow)
+	}
+	return defaultDueSoonWindow
+}
+
+// intervalo configuravel por DUE_REMINDER_INTERVAL
+func dueReminderInterval() time.Duration {
+	if raw := os.Getenv("DUE_REMINDER_INTERVAL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Aviso: DUE_REMINDER_INTERVAL inválido (%s), usando %s", raw, defaultDueReminderInterval)
+	}
+	return defaultDueReminderInterval
+}
+
+// card com prazo que ainda precisa de lembrete
+type dueReminder struct {
+	CardID     int
+	BoardID    int
+	Title      string
+	AssignedTo string
+	DueDate    time.Time
+	Kind       string
+}
+
+// cards ativos, com responsavel, fora de colunas de conclusao, vencidos ou vencendo ate now+window,
+// sem lembrete do mesmo tipo para o mesmo prazo
+const dueRemindersQuery = `
+SELECT c.id, col.board_id, c.title, c.assigned_to, c.due_date,
+       CASE WHEN c.due_date <= $1 THEN 'overdue' ELSE 'due_soon' END AS kind
+FROM cards c
+JOIN columns col ON col.id = c.column_id
+JOIN boards b ON b.id = col.board_id
+WHERE c.due_date IS NOT NULL AND c.due_date <= $2
+  AND c.deleted_at IS NULL AND c.archived_at IS NULL
+  AND col.deleted_at IS NULL AND b.deleted_at IS NULL
+  AND col.category NOT IN ('done_success', 'done_failure')
+  AND COALESCE(c.assigned_to, '') <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM card_due_reminders r
+      WHERE r.card_id = c.id AND r.due_date = c.due_date
+        AND r.kind = CASE WHEN c.due_date <= $1 THEN 'overdue' ELSE 'due_soon' END
+  )
+ORDER BY c.due_date`
+
+// criar as notificacoes due_soon/overdue pendentes; devolve quantas foram enviadas
+func (app *App) sendDueReminders(ctx context.Context, now time.Time, window time.Duration) (int, error) {
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, dueRemindersQuery, now, now.Add(window))
+	if err != nil {
+		return 0, err
+	}
+	pending := make([]dueReminder, 0)
+	for rows.Next() {
+		var r dueReminder
+		if err := rows.Scan(&r.CardID, &r.BoardID, &r.Title, &r.AssignedTo, &r.DueDate, &r.Kind); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, r := range pending {
+		// responsavel desconhecido fica sem registro: volta a ser tentado quando o username for corrigido
+		assigneeID, err := app.getUserIDByUsername(r.AssignedTo)
+		if err != nil {
+			continue
+		}
+		// outra instancia pode ter registrado o mesmo lembrete
+		tag, err := tx.Exec(ctx,
+			"INSERT INTO card_due_reminders (card_id, kind, due_date) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			r.CardID, r.Kind, r.DueDate)
+		if err != nil {
+			return 0, err
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		due := r.DueDate.In(time.Local).Format("02/01/2006 15:04")
+		message := fmt.Sprintf("A tarefa \"%s\" vence em %s", r.Title, due)
+		if r.Kind == ReminderOverdue {
+			message = fmt.Sprintf("A tarefa \"%s\" está atrasada (prazo: %s)", r.Title, due)
+		}
+		err = app.createNotification(tx, Notification{
+			UserID:         assigneeID,
+			Type:           r.Kind,
+			Message:        message,
+			RelatedBoardID: &r.BoardID,
+			RelatedCardID:  &r.CardID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		sent++
+	}
+	return sent, tx.Commit(ctx)
+}
+
+// varredura periodica de prazos
+func (app *App) remindDueCards(ctx context.Context) {
+	window := dueSoonWindow()
+	ticker := time.NewTicker(dueReminderInterval())
+	defer ticker.Stop()
+	for {
+		sent, err := app.sendDueReminders(ctx, time.Now(), window)
+		if err != nil {
+			log.Printf("Erro ao enviar lembretes de prazo: %v", err)
+		} else if sent > 0 {
+			log.Printf("Lembretes de prazo enviados: %d", sent)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
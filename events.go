@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maximo de eventos reenviados numa reconexao; acima disso o cliente recarrega
+const maxReplayEvents = 500
+
+// retencao padrao dos eventos de board
+const defaultBoardEventsRetention = 72 * time.Hour
+
+// lock por board para que a ordem do broadcast siga a ordem do seq
+func (app *App) boardEventLock(boardID int) *sync.Mutex {
+	app.eventLocks.mu.Lock()
+	defer app.eventLocks.mu.Unlock()
+	if app.eventLocks.locks == nil {
+		app.eventLocks.locks = make(map[int]*sync.Mutex)
+	}
+	lock, ok := app.eventLocks.locks[boardID]
+	if !ok {
+		lock = &sync.Mutex{}
+		app.eventLocks.locks[boardID] = lock
+	}
+	return lock
+}
+
+// mensagens iniciais de uma conexao: eventos perdidos desde ?since= ou RESYNC_REQUIRED
+func (app *App) boardEventsBacklog(boardID int, since int64, resume bool) [][]byte {
+	lastSeq, err := app.events.LastBoardEventSeq(context.Background(), boardID)
+	if err != nil {
+		log.Printf("Erro ao buscar último seq do board %d: %v", boardID, err)
+		return [][]byte{encodeWsMessage(WsMessage{Type: "RESYNC_REQUIRED", Payload: fiber.Map{"last_seq": lastSeq}})}
+	}
+
+	if !resume {
+		return [][]byte{encodeWsMessage(WsMessage{Type: "CONNECTED", Payload: fiber.Map{"last_seq": lastSeq}})}
+	}
+	if since == lastSeq {
+		return nil
+	}
+
+	resync := [][]byte{encodeWsMessage(WsMessage{Type: "RESYNC_REQUIRED", Payload: fiber.Map{"last_seq": lastSeq}})}
+	if since > lastSeq || lastSeq-since > maxReplayEvents {
+		return resync
+	}
+	events, err := app.events.ListBoardEvents(context.Background(), boardID, since, lastSeq)
+	if err != nil {
+		log.Printf("Erro ao buscar eventos perdidos do board %d: %v", boardID, err)
+		return resync
+	}
+	// o seq nao tem buracos: faltar evento no intervalo e sinal de que a retencao ja o apagou
+	if int64(len(events)) != lastSeq-since {
+		return resync
+	}
+	backlog := make([][]byte, 0, len(events))
+	for _, message := range events {
+		backlog = append(backlog, encodeWsMessage(message))
+	}
+	return backlog
+}
+
+func encodeWsMessage(message WsMessage) []byte {
+	payloadBytes, _ := json.Marshal(message)
+	return payloadBytes
+}
+
+// retencao dos eventos, configuravel por BOARD_EVENTS_RETENTION (ex: 72h)
+func boardEventsRetention() time.Duration {
+	if raw := os.Getenv("BOARD_EVENTS_RETENTION"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Aviso: BOARD_EVENTS_RETENTION inválido (%s), usando %s", raw, defaultBoardEventsRetention)
+	}
+	return defaultBoardEventsRetention
+}
+
+// limpeza periodica dos eventos antigos
+func (app *App) purgeBoardEvents(ctx context.Context) {
+	retention := boardEventsRetention()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-retention)
+		cmdTag, err := app.db.Exec(ctx, "DELETE FROM board_events WHERE created_at < $1", cutoff)
+		if err != nil {
+			log.Printf("Erro ao limpar eventos antigos de boards: %v", err)
+		} else if cmdTag.RowsAffected() > 0 {
+			log.Printf("Eventos de boards removidos pela retenção: %d", cmdTag.RowsAffected())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parsear ?since= do websocket
+func parseSince(raw string) (int64, bool) {
+	if raw == "" {
+		return 0, false
+	}
+	since, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || since < 0 {
+		return 0, false
+	}
+	return since, true
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// env sem Postgres: stores em memoria e o JWT do Supabase validado so pelo segredo
+func newMemoryTestEnv(t *testing.T) (*testEnv, *MemoryStore) {
+	t.Helper()
+	store := newMemoryStore()
+	app := &App{hub: newHub()}
+	app.useStores(store)
+	app.useIdentity(&SupabaseIdentity{JWTSecret: testJWTSecret})
+	app.users = store
+	return &testEnv{t: t, app: app, fiber: app.newFiberApp()}, store
+}
+
+// board com as colunas A fazer (backlog), Fazendo e Feito (done_success)
+func seedMemoryBoard(store *MemoryStore, ownerID string) (Board, []Column) {
+	board := store.AddBoard(Board{Title: "Suporte", OwnerID: ownerID})
+	columns := []Column{
+		store.AddColumn(Column{BoardID: board.ID, Title: "A fazer", Position: 0, Category: CategoryBacklog}),
+		store.AddColumn(Column{BoardID: board.ID, Title: "Fazendo", Position: 1, Category: CategoryInProgress}),
+		store.AddColumn(Column{BoardID: board.ID, Title: "Feito", Position: 2, Category: CategoryDoneSuccess}),
+	}
+	return board, columns
+}
+
+func eventTypes(store *MemoryStore, boardID int) []string {
+	types := make([]string, 0)
+	for _, message := range store.events[boardID] {
+		types = append(types, message.Type)
+	}
+	return types
+}
+
+func TestMemoryColumnHandlers(t *testing.T) {
+	e, store := newMemoryTestEnv(t)
+	owner := store.AddUser(User{ID: "u-dono", Username: "dono"}).ID
+	viewer := store.AddUser(User{ID: "u-leitor", Username: "leitor"}).ID
+	board, cols := seedMemoryBoard(store, owner)
+	store.AddMember(board.ID, viewer, BoardRoleViewer)
+
+	var created Column
+	e.expect(http.StatusCreated, "POST", "/api/columns", owner, fiber.Map{"board_id": board.ID, "title": "Validação"}, &created)
+	if created.Position != 3 || created.Category != CategoryInProgress {
+		t.Fatalf("coluna nova deveria ir para o fim, em andamento: %+v", created)
+	}
+	e.expect(http.StatusForbidden, "POST", "/api/columns", viewer, fiber.Map{"board_id": board.ID, "title": "Não"}, nil)
+
+	var updated Column
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/columns/%d", created.ID), owner, fiber.Map{"title": "Revisão", "color": "#fff"}, &updated)
+	if updated.Title != "Revisão" || updated.Category != CategoryInProgress {
+		t.Fatalf("sem category a coluna mantem a atual: %+v", updated)
+	}
+
+	order := []int{created.ID, cols[2].ID, cols[0].ID, cols[1].ID}
+	e.expect(http.StatusNoContent, "POST", fmt.Sprintf("/api/boards/%d/columns/reorder", board.ID), owner, fiber.Map{"ordered_column_ids": order}, nil)
+	for i, col := range e.columns(board.ID, viewer) {
+		if col.ID != order[i] || col.Position != i {
+			t.Fatalf("ordem das colunas depois do reorder: %+v", e.columns(board.ID, owner))
+		}
+	}
+
+	e.createCard(cols[0].ID, owner, "Chamado")
+	e.expect(http.StatusBadRequest, "DELETE", fmt.Sprintf("/api/columns/%d", cols[0].ID), owner, nil, nil)
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/columns/%d", cols[2].ID), owner, nil, nil)
+	remaining := e.columns(board.ID, owner)
+	if len(remaining) != 3 || remaining[1].ID != cols[0].ID || remaining[1].Position != 1 {
+		t.Fatalf("excluir a coluna deveria fechar o buraco na ordem: %+v", remaining)
+	}
+
+	want := []string{"COLUMN_CREATED", "COLUMN_UPDATED", "COLUMNS_REORDERED", "CARD_CREATED", "BOARD_STATE_UPDATED"}
+	if got := eventTypes(store, board.ID); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("eventos do board = %v, esperado %v", got, want)
+	}
+}
+
+func TestMemoryCardHandlers(t *testing.T) {
+	e, store := newMemoryTestEnv(t)
+	owner := store.AddUser(User{ID: "u-dono", Username: "dono"}).ID
+	helper := store.AddUser(User{ID: "u-ajudante", Username: "ajudante"}).ID
+	board, cols := seedMemoryBoard(store, owner)
+	todo, doing, done := cols[0].ID, cols[1].ID, cols[2].ID
+
+	a := e.createCard(todo, owner, "A")
+	e.createCard(todo, owner, "B")
+	c := e.createCard(todo, owner, "C")
+	assertColumn(t, e.cards(todo, owner), "A", "B", "C")
+
+	// atualizar nao troca de coluna e avisa o novo responsavel
+	e.expect(http.StatusConflict, "PUT", fmt.Sprintf("/api/cards/%d", a.ID), owner, fiber.Map{"title": "A", "column_id": doing}, nil)
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/cards/%d", a.ID), owner, fiber.Map{"title": "A1", "assigned_to": "ajudante"}, nil)
+	notifications, _ := store.ListNotifications(context.Background(), helper)
+	if len(notifications) != 1 || notifications[0].RelatedCardID == nil || *notifications[0].RelatedCardID != a.ID {
+		t.Fatalf("o responsável deveria ser notificado: %+v", notifications)
+	}
+
+	e.moveCard(owner, c.ID, doing, 0)
+	assertColumn(t, e.cards(todo, owner), "A1", "B")
+	assertColumn(t, e.cards(doing, owner), "C")
+
+	// entrar na coluna done conclui; sair reabre
+	e.moveCard(owner, c.ID, done, 0)
+	if moved := e.cards(done, owner); len(moved) != 1 || moved[0].CompletedAt == nil {
+		t.Fatalf("card na coluna done_success deveria ter completed_at: %+v", moved)
+	}
+	e.moveCard(owner, c.ID, todo, e.cards(todo, owner)[0].Position)
+	assertColumn(t, e.cards(todo, owner), "C", "A1", "B")
+	if back := e.cards(todo, owner); back[0].CompletedAt != nil {
+		t.Fatalf("card que saiu da coluna done deveria perder completed_at: %+v", back[0])
+	}
+
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/cards/%d", a.ID), owner, nil, nil)
+	assertColumn(t, e.cards(todo, owner), "C", "B")
+	if _, ok := store.trashedCards[a.ID]; !ok {
+		t.Fatal("card excluído deveria ir para a lixeira")
+	}
+
+	var actions []string
+	for _, activity := range store.activity {
+		if activity.CardID == c.ID {
+			actions = append(actions, activity.Action)
+		}
+	}
+	if fmt.Sprint(actions) != "[created moved moved moved]" {
+		t.Fatalf("histórico do card C = %v", actions)
+	}
+
+	// replay pela mesma sequencia que o hub entrega
+	backlog := e.app.boardEventsBacklog(board.ID, 3, true)
+	if len(backlog) != len(store.events[board.ID])-3 {
+		t.Fatalf("backlog deveria reenviar os eventos depois do seq 3: %d", len(backlog))
+	}
+	var first WsMessage
+	json.Unmarshal(backlog[0], &first)
+	if first.Seq != 4 || first.Type != "CARD_UPDATED" {
+		t.Fatalf("primeiro evento do backlog = %+v", first)
+	}
+}
+
+func TestMemoryMoveCardRules(t *testing.T) {
+	e, store := newMemoryTestEnv(t)
+	owner := store.AddUser(User{ID: "u-dono", Username: "dono"}).ID
+	board, cols := seedMemoryBoard(store, owner)
+	other := store.AddBoard(Board{Title: "Outro", OwnerID: owner})
+	otherCol := store.AddColumn(Column{BoardID: other.ID, Title: "Entrada"})
+	todo, doing := cols[0].ID, cols[1].ID
+
+	limit := 1
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/columns/%d/policy", doing), owner, fiber.Map{"wip_limit": limit, "require_assignee": true}, nil)
+	first := e.createCard(todo, owner, "Primeiro")
+	second := e.createCard(todo, owner, "Segundo")
+
+	var violation ColumnPolicyViolation
+	e.expect(http.StatusConflict, "POST", "/api/cards/move", owner, fiber.Map{"card_id": first.ID, "new_column_id": doing, "new_position": 0}, &violation)
+	if violation.Rule != RuleRequireAssignee {
+		t.Fatalf("card sem responsável deveria ser barrado: %+v", violation)
+	}
+	for _, card := range []Card{first, second} {
+		e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/cards/%d", card.ID), owner, fiber.Map{"title": card.Title, "assigned_to": "dono"}, nil)
+	}
+	e.moveCard(owner, first.ID, doing, 0)
+	e.expect(http.StatusConflict, "POST", "/api/cards/move", owner, fiber.Map{"card_id": second.ID, "new_column_id": doing, "new_position": 0}, &violation)
+	if violation.Rule != RuleWipLimit || violation.CardCount == nil || *violation.CardCount != 1 {
+		t.Fatalf("limite duro deveria barrar o segundo card: %+v", violation)
+	}
+
+	// limite suave deixa entrar e avisa o board
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/columns/%d/policy", doing), owner, fiber.Map{"wip_limit": limit, "wip_soft": true}, nil)
+	e.moveCard(owner, second.ID, doing, 1)
+	assertColumn(t, e.cards(doing, owner), "Primeiro", "Segundo")
+	if types := eventTypes(store, board.ID); types[len(types)-1] != "WIP_EXCEEDED" {
+		t.Fatalf("limite suave estourado deveria gerar WIP_EXCEEDED: %v", types)
+	}
+
+	// entre boards, os dois ficam sabendo
+	e.moveCard(owner, first.ID, otherCol.ID, 0)
+	assertColumn(t, e.cards(doing, owner), "Segundo")
+	for _, boardID := range []int{board.ID, other.ID} {
+		if types := eventTypes(store, boardID); types[len(types)-1] != "CARD_MOVED" {
+			t.Fatalf("board %d deveria receber o CARD_MOVED: %v", boardID, types)
+		}
+	}
+
+	e.expect(http.StatusNotFound, "POST", "/api/cards/move", owner, fiber.Map{"card_id": second.ID, "new_column_id": 9999, "new_position": 0}, nil)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// parametros websocket
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsMaxMessageSize = 4096
+	wsSendBufferSize = 64
+)
+
+// estrutura wsclient
+type wsClient struct {
+	conn    *websocket.Conn
+	boardID int
+	userID  string
+	send    chan []byte
+}
+
+// estrutura hub
+type Hub struct {
+	mu     sync.RWMutex
+	boards map[int]map[*wsClient]struct{}
+	// relay opcional para rodar varias instancias; nil = instancia unica
+	relay relay
+}
+
+func newHub() *Hub {
+	return &Hub{boards: make(map[int]map[*wsClient]struct{})}
+}
+
+// registrar conexao
+func (h *Hub) register(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.boards[client.boardID] == nil {
+		h.boards[client.boardID] = make(map[*wsClient]struct{})
+		if h.relay != nil {
+			h.relay.subscribe(client.boardID)
+		}
+	}
+	h.boards[client.boardID][client] = struct{}{}
+}
+
+// derrubar as conexoes de um usuario que perdeu acesso ao board, em todas as instancias
+func (h *Hub) disconnectUser(boardID int, userID string) {
+	h.dropUser(boardID, userID)
+	h.publishControl(boardID, relayControl{Control: controlDisconnectUser, UserID: userID})
+}
+
+// fechar todas as conexoes de um board excluido, em todas as instancias
+func (h *Hub) closeBoard(boardID int) {
+	h.dropBoard(boardID)
+	h.publishControl(boardID, relayControl{Control: controlCloseBoard})
+}
+
+// aplicar um controle recebido de outra instancia
+func (h *Hub) applyControl(boardID int, control relayControl) {
+	switch control.Control {
+	case controlDisconnectUser:
+		h.dropUser(boardID, control.UserID)
+	case controlCloseBoard:
+		h.dropBoard(boardID)
+	default:
+		log.Printf("Aviso: controle desconhecido no board %d: %s", boardID, control.Control)
+	}
+}
+
+func (h *Hub) publishControl(boardID int, control relayControl) {
+	if h.relay == nil {
+		return
+	}
+	control.ID = newMessageID()
+	payload, err := json.Marshal(control)
+	if err != nil {
+		log.Printf("Erro ao serializar controle %s: %v", control.Control, err)
+		return
+	}
+	h.relay.publish(boardID, control.ID, payload)
+}
+
+// conexoes locais do usuario no board
+func (h *Hub) dropUser(boardID int, userID string) {
+	h.drop(boardID, "ACCESS_REVOKED", func(client *wsClient) bool { return client.userID == userID })
+}
+
+// todas as conexoes locais do board
+func (h *Hub) dropBoard(boardID int) {
+	h.drop(boardID, "BOARD_CLOSED", func(*wsClient) bool { return true })
+}
+
+// avisa o motivo (se houver espaco na fila) e fecha as conexoes escolhidas
+func (h *Hub) drop(boardID int, reason string, match func(*wsClient) bool) {
+	notice := encodeWsMessage(WsMessage{Type: reason})
+	var targets []*wsClient
+	h.mu.RLock()
+	for client := range h.boards[boardID] {
+		if match(client) {
+			select {
+			case client.send <- notice:
+			default:
+			}
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		h.unregister(client)
+	}
+}
+
+// remover conexao, fecha a fila de saida uma unica vez
+func (h *Hub) unregister(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clients, ok := h.boards[client.boardID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+	delete(clients, client)
+	close(client.send)
+	if len(clients) == 0 {
+		delete(h.boards, client.boardID)
+		if h.relay != nil {
+			h.relay.unsubscribe(client.boardID)
+		}
+	}
+}
+
+// enfileirar mensagem para todas as conexoes do board
+func (h *Hub) broadcast(boardID int, payload []byte) {
+	var slow []*wsClient
+	h.mu.RLock()
+	for client := range h.boards[boardID] {
+		select {
+		case client.send <- payload:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range slow {
+		log.Printf("Aviso: conexão WebSocket lenta removida do board %d", boardID)
+		h.unregister(client)
+	}
+}
+
+// maior seq coberto pelas mensagens iniciais (eventos reenviados ou o last_seq de CONNECTED/RESYNC_REQUIRED)
+func replayedSeq(initial [][]byte) int64 {
+	var last int64
+	for _, raw := range initial {
+		var message struct {
+			Seq     int64           `json:"seq"`
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if json.Unmarshal(raw, &message) != nil {
+			continue
+		}
+		seq := message.Seq
+		if message.Type == "CONNECTED" || message.Type == "RESYNC_REQUIRED" {
+			var payload struct {
+				LastSeq int64 `json:"last_seq"`
+			}
+			json.Unmarshal(message.Payload, &payload)
+			seq = payload.LastSeq
+		}
+		if seq > last {
+			last = seq
+		}
+	}
+	return last
+}
+
+// mensagens ao vivo que chegaram durante o calculo do backlog, sem as que ele ja cobriu
+func pendingLive(initial, live [][]byte) [][]byte {
+	last := replayedSeq(initial)
+	pending := make([][]byte, 0, len(live))
+	for _, raw := range live {
+		var message struct {
+			Seq int64 `json:"seq"`
+		}
+		if json.Unmarshal(raw, &message) == nil && message.Seq != 0 && message.Seq <= last {
+			continue
+		}
+		pending = append(pending, raw)
+	}
+	return pending
+}
+
+// goroutine de escrita por conexao; ja drena a fila enquanto o backlog e calculado,
+// e o backlog sai antes das mensagens ao vivo
+func (client *wsClient) writePump(backlog <-chan [][]byte) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+	var initial, live [][]byte
+	for waiting := true; waiting; {
+		select {
+		case initial = <-backlog:
+			waiting = false
+		case message, ok := <-client.send:
+			if !ok {
+				client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			live = append(live, message)
+		}
+	}
+	for _, message := range append(initial, pendingLive(initial, live)...) {
+		client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case message, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// leitura ate a conexao cair, mantendo o heartbeat
+func (client *wsClient) readPump() {
+	client.conn.SetReadLimit(wsMaxMessageSize)
+	client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		if _, _, err := client.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// atender conexao ate o fim; o handler so pode retornar depois que a
+// goroutine de escrita terminar, pois o fiber recicla o *websocket.Conn.
+// O backlog e calculado depois do registro para nao perder eventos no meio;
+// a escrita ja comeca antes dele para a fila nao encher durante a consulta.
+func (h *Hub) serve(conn *websocket.Conn, boardID int, userID string, backlog func() [][]byte) {
+	client := &wsClient{
+		conn:    conn,
+		boardID: boardID,
+		userID:  userID,
+		send:    make(chan []byte, wsSendBufferSize),
+	}
+	h.register(client)
+	initial := make(chan [][]byte, 1)
+	done := make(chan struct{})
+	go func() {
+		client.writePump(initial)
+		close(done)
+	}()
+	if backlog != nil {
+		initial <- backlog()
+	} else {
+		initial <- nil
+	}
+	client.readPump()
+	h.unregister(client)
+	<-done
+}
+
+// serializar, entregar localmente e repassar as outras instancias
+func (h *Hub) broadcastMessage(boardID int, message WsMessage) {
+	if message.ID == "" {
+		message.ID = newMessageID()
+	}
+	payloadBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Erro ao serializar mensagem WebSocket (%s): %v", message.Type, err)
+		return
+	}
+	h.broadcast(boardID, payloadBytes)
+	if h.relay != nil {
+		h.relay.publish(boardID, message.ID, payloadBytes)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// relay falso que so registra as chamadas
+type fakeRelay struct {
+	mu           sync.Mutex
+	subscribed   map[int]int
+	unsubscribed map[int]int
+	published    []string
+}
+
+func newFakeRelay() *fakeRelay {
+	return &fakeRelay{subscribed: make(map[int]int), unsubscribed: make(map[int]int)}
+}
+
+func (r *fakeRelay) publish(boardID int, messageID string, payload []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, string(payload))
+}
+
+func (r *fakeRelay) subscribe(boardID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribed[boardID]++
+}
+
+func (r *fakeRelay) unsubscribe(boardID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsubscribed[boardID]++
+}
+
+// cliente sem conexao: register, unregister e broadcast so mexem na fila
+func testClient(boardID int, userID string) *wsClient {
+	return &wsClient{boardID: boardID, userID: userID, send: make(chan []byte, wsSendBufferSize)}
+}
+
+// fila fechada e o que sobrou nela
+func drained(t *testing.T, client *wsClient) []string {
+	t.Helper()
+	var messages []string
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, string(message))
+		default:
+			t.Fatalf("fila do cliente %s deveria estar fechada", client.userID)
+		}
+	}
+}
+
+func (h *Hub) clientCount(boardID int) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.boards[boardID])
+}
+
+func TestHubRegisterAndUnregister(t *testing.T) {
+	h := newHub()
+	r := newFakeRelay()
+	h.relay = r
+	ana, bia := testClient(1, "ana"), testClient(1, "bia")
+
+	h.register(ana)
+	h.register(bia)
+	if h.clientCount(1) != 2 || r.subscribed[1] != 1 {
+		t.Fatalf("dois clientes e uma assinatura: %d clientes, %d assinaturas", h.clientCount(1), r.subscribed[1])
+	}
+
+	h.broadcast(1, []byte("oi"))
+	h.broadcast(2, []byte("outro board"))
+	if got := <-ana.send; string(got) != "oi" {
+		t.Fatalf("ana recebeu %q", got)
+	}
+
+	h.unregister(ana)
+	h.unregister(ana)
+	if drained(t, ana) != nil || h.clientCount(1) != 1 || r.unsubscribed[1] != 0 {
+		t.Fatal("sair uma vez fecha a fila e mantem o board assinado enquanto houver clientes")
+	}
+	h.unregister(bia)
+	if h.clientCount(1) != 0 || r.unsubscribed[1] != 1 {
+		t.Fatalf("ultimo cliente deveria cancelar a assinatura: %d", r.unsubscribed[1])
+	}
+	if got := drained(t, bia); len(got) != 1 || got[0] != "oi" {
+		t.Fatalf("bia deveria ter recebido so a mensagem do board dela: %v", got)
+	}
+}
+
+func TestHubEvictsSlowConsumer(t *testing.T) {
+	h := newHub()
+	slow, fast := testClient(1, "lento"), testClient(1, "rapido")
+	h.register(slow)
+	h.register(fast)
+
+	for i := 0; i < wsSendBufferSize; i++ {
+		h.broadcast(1, []byte("x"))
+		<-fast.send
+	}
+	// a fila do lento encheu: a proxima mensagem o derruba sem travar os outros
+	h.broadcast(1, []byte("y"))
+	if got := drained(t, slow); len(got) != wsSendBufferSize {
+		t.Fatalf("cliente lento deveria sair com a fila cheia: %d mensagens", len(got))
+	}
+	if got := <-fast.send; string(got) != "y" || h.clientCount(1) != 1 {
+		t.Fatalf("cliente rapido deveria continuar: %q, %d clientes", got, h.clientCount(1))
+	}
+}
+
+func TestHubDisconnectUserAndCloseBoard(t *testing.T) {
+	h := newHub()
+	r := newFakeRelay()
+	h.relay = r
+	ana, anaTab, bia := testClient(1, "ana"), testClient(1, "ana"), testClient(1, "bia")
+	other := testClient(2, "ana")
+	for _, c := range []*wsClient{ana, anaTab, bia, other} {
+		h.register(c)
+	}
+
+	h.disconnectUser(1, "ana")
+	for _, c := range []*wsClient{ana, anaTab} {
+		if got := drained(t, c); len(got) != 1 || got[0] != string(encodeWsMessage(WsMessage{Type: "ACCESS_REVOKED"})) {
+			t.Fatalf("ana deveria receber ACCESS_REVOKED antes de cair: %v", got)
+		}
+	}
+	if h.clientCount(1) != 1 || h.clientCount(2) != 1 {
+		t.Fatalf("so as conexoes da ana no board 1 caem: %d e %d", h.clientCount(1), h.clientCount(2))
+	}
+
+	h.closeBoard(1)
+	if got := drained(t, bia); len(got) != 1 || got[0] != string(encodeWsMessage(WsMessage{Type: "BOARD_CLOSED"})) {
+		t.Fatalf("bia deveria receber BOARD_CLOSED: %v", got)
+	}
+	if h.clientCount(2) != 1 {
+		t.Fatal("outros boards continuam abertos")
+	}
+
+	// os dois controles foram para as outras instancias
+	if len(r.published) != 2 {
+		t.Fatalf("controles publicados: %v", r.published)
+	}
+	var first, second relayControl
+	json.Unmarshal([]byte(r.published[0]), &first)
+	json.Unmarshal([]byte(r.published[1]), &second)
+	if first.Control != controlDisconnectUser || first.UserID != "ana" || first.ID == "" || second.Control != controlCloseBoard {
+		t.Fatalf("controles inesperados: %+v %+v", first, second)
+	}
+}
+
+func TestPendingLiveSkipsReplayedEvents(t *testing.T) {
+	event := func(seq int64) []byte {
+		return encodeWsMessage(WsMessage{Seq: seq, Type: "CARD_UPDATED"})
+	}
+	noSeq := encodeWsMessage(WsMessage{Type: "BOARD_STATE_UPDATED"})
+
+	// reconexao: o backlog reenviou ate o 5; o que chegou ao vivo no meio e ja estava nele cai
+	got := pendingLive([][]byte{event(4), event(5)}, [][]byte{event(5), event(6), noSeq})
+	if len(got) != 2 || string(got[0]) != string(event(6)) || string(got[1]) != string(noSeq) {
+		t.Fatalf("pendentes depois do backlog: %q", got)
+	}
+
+	// conexao nova: CONNECTED informa o last_seq
+	connected := encodeWsMessage(WsMessage{Type: "CONNECTED", Payload: map[string]int64{"last_seq": 9}})
+	got = pendingLive([][]byte{connected}, [][]byte{event(9), event(10)})
+	if len(got) != 1 || string(got[0]) != string(event(10)) {
+		t.Fatalf("pendentes depois do CONNECTED: %q", got)
+	}
+	if got := pendingLive(nil, [][]byte{event(1)}); len(got) != 1 {
+		t.Fatalf("sem backlog nada e descartado: %q", got)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// servidor sem o segredo/chave para validar tokens
+var ErrAuthConfig = errors.New("Configuração do servidor incorreta")
+
+// IdentityProvider valida tokens e e a fonte dos dados dos usuarios
+type IdentityProvider interface {
+	UserStore
+	// ValidateToken confere o JWT e devolve o ID do usuario
+	ValidateToken(tokenString string) (string, error)
+}
+
+// escolher provedor por AUTH_PROVIDER (supabase | local)
+func newIdentityFromEnv(db *pgxpool.Pool) (IdentityProvider, error) {
+	leeway, err := jwtLeewayFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	switch provider := os.Getenv("AUTH_PROVIDER"); provider {
+	case "", "supabase":
+		return newSupabaseIdentityFromEnv(db, leeway), nil
+	case "local":
+		local, err := newLocalIdentityFromEnv(db)
+		if err != nil {
+			return nil, err
+		}
+		local.Leeway = leeway
+		return local, nil
+	default:
+		return nil, fmt.Errorf("AUTH_PROVIDER desconhecido: %s", provider)
+	}
+}
+
+// tolerancia de relogio na validacao de exp/nbf/iat (JWT_CLOCK_SKEW, padrao 0)
+func jwtLeewayFromEnv() (time.Duration, error) {
+	raw := os.Getenv("JWT_CLOCK_SKEW")
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("JWT_CLOCK_SKEW inválido: %q", raw)
+	}
+	return d, nil
+}
+
+// apontar o App para o provedor, que tambem passa a ser o UserStore
+func (app *App) useIdentity(p IdentityProvider) {
+	app.identity = p
+	app.users = p
+}
+
+// validar um JWT HS256 e devolver o sub
+func parseHS256Token(tokenString, secret string, opts ...jwt.ParserOption) (string, error) {
+	if secret == "" {
+		return "", ErrAuthConfig
+	}
+	opts = append(opts, jwt.WithValidMethods([]string{"HS256"}))
+	return parseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, opts...)
+}
+
+// validar um JWT com a chave escolhida por keyFunc; mensagens iguais para todos os provedores
+func parseToken(tokenString string, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &SupabaseClaims{}, keyFunc, opts...)
+	if err != nil {
+		if errors.Is(err, ErrAuthConfig) {
+			return "", ErrAuthConfig
+		}
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return "", errors.New("Token expirado")
+		}
+		return "", errors.New("Token inválido")
+	}
+	if !token.Valid {
+		return "", errors.New("Token inválido ou expirado")
+	}
+	claims, ok := token.Claims.(*SupabaseClaims)
+	if !ok || claims.UserID == "" {
+		return "", errors.New("Claims do token inválidas ou ID de usuário ausente")
+	}
+	return claims.UserID, nil
+}
+
+// SupabaseIdentity usa o Supabase Auth: tokens HS256 (segredo legado) ou RS256/ES256 (JWKS) e usuarios em auth.users
+type SupabaseIdentity struct {
+	db        *pgxpool.Pool
+	JWTSecret string
+	// nil = tokens assimetricos recusados
+	JWKS *JWKS
+	// vazio = iss nao e conferido
+	Issuer string
+	Leeway time.Duration
+}
+
+// SUPABASE_JWKS_URL ou SUPABASE_JWKS_FILE; sem nenhum, o JWKS padrao do SUPABASE_PROJECT_URL
+func newSupabaseIdentityFromEnv(db *pgxpool.Pool, leeway time.Duration) *SupabaseIdentity {
+	s := &SupabaseIdentity{
+		db:        db,
+		JWTSecret: os.Getenv("SUPABASE_JWT_SECRET"),
+		Issuer:    os.Getenv("SUPABASE_JWT_ISSUER"),
+		Leeway:    leeway,
+	}
+	jwksURL, jwksFile := os.Getenv("SUPABASE_JWKS_URL"), os.Getenv("SUPABASE_JWKS_FILE")
+	if jwksURL == "" && jwksFile == "" {
+		if projectURL := strings.TrimRight(os.Getenv("SUPABASE_PROJECT_URL"), "/"); projectURL != "" {
+			jwksURL = projectURL + "/auth/v1/.well-known/jwks.json"
+		}
+	}
+	if jwksURL != "" || jwksFile != "" {
+		s.JWKS = newJWKS(jwksURL, jwksFile)
+	}
+	return s
+}
+
+func (s *SupabaseIdentity) ValidateToken(tokenString string) (string, error) {
+	if s.JWTSecret == "" && s.JWKS == nil {
+		return "", ErrAuthConfig
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithAudience("authenticated"),
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithLeeway(s.Leeway),
+	}
+	if s.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.Issuer))
+	}
+	return parseToken(tokenString, s.signingKey, opts...)
+}
+
+// segredo para HS256, chave do JWKS (pelo kid) para RS256/ES256
+func (s *SupabaseIdentity) signingKey(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if s.JWTSecret == "" {
+			return nil, errors.New("segredo HS256 não configurado")
+		}
+		return []byte(s.JWTSecret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if s.JWKS == nil {
+			return nil, errors.New("JWKS não configurado")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.JWKS.Key(kid)
+	default:
+		return nil, fmt.Errorf("método de assinatura inesperado: %v", token.Header["alg"])
+	}
+}
+
+const supabaseUserColumns = `
+	id,
+	email,
+	COALESCE(raw_user_meta_data->>'username', email) as username,
+	COALESCE(raw_user_meta_data->>'avatar_url', '') as avatar,
+	created_at,
+	COALESCE(role, '') as role,
+	COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) as is_admin`
+
+func (s *SupabaseIdentity) GetUser(ctx context.Context, userID string) (User, error) {
+	var user User
+	err := s.db.QueryRow(ctx, "SELECT "+supabaseUserColumns+" FROM auth.users WHERE id = $1", userID).Scan(
+		&user.ID, &user.Email, &user.Username, &user.Avatar, &user.CreatedAt, &user.Role, &user.IsAdmin)
+	return user, notFound(err)
+}
+
+func (s *SupabaseIdentity) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+supabaseUserColumns+" FROM auth.users ORDER BY email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]User, 0)
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Avatar, &user.CreatedAt, &user.Role, &user.IsAdmin); err != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *SupabaseIdentity) UserIDByUsername(ctx context.Context, username string) (string, error) {
+	var userID string
+	query := `SELECT id FROM auth.users WHERE raw_user_meta_data->>'username' = $1 OR email = $1 LIMIT 1`
+	err := s.db.QueryRow(ctx, query, username).Scan(&userID)
+	return userID, notFound(err)
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// emissor dos tokens do provedor local
+const localTokenIssuer = "taskhub-local"
+
+var (
+	ErrInvalidCredentials  = errors.New("Credenciais inválidas")
+	ErrInvalidRefreshToken = errors.New("Refresh token inválido ou expirado")
+)
+
+// hash usado quando o login nao existe, para o tempo de resposta nao denunciar o usuario
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("taskhub-dummy-password"), bcrypt.DefaultCost)
+
+// estrutura authsession: resposta de login/refresh
+type AuthSession struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
+}
+
+// CredentialsProvider e implementado pelos provedores que fazem login com senha
+type CredentialsProvider interface {
+	Login(ctx context.Context, login, password string) (AuthSession, error)
+	Refresh(ctx context.Context, refreshToken string) (AuthSession, error)
+}
+
+// LocalIdentity guarda usuarios em local_users e emite os proprios JWTs HS256
+type LocalIdentity struct {
+	db         *pgxpool.Pool
+	JWTSecret  string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Leeway     time.Duration
+}
+
+// LOCAL_AUTH_JWT_SECRET, LOCAL_AUTH_ACCESS_TTL (padrao 15m), LOCAL_AUTH_REFRESH_TTL (padrao 720h)
+func newLocalIdentityFromEnv(db *pgxpool.Pool) (*LocalIdentity, error) {
+	p := &LocalIdentity{
+		db:         db,
+		JWTSecret:  os.Getenv("LOCAL_AUTH_JWT_SECRET"),
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 30 * 24 * time.Hour,
+	}
+	for env, ttl := range map[string]*time.Duration{"LOCAL_AUTH_ACCESS_TTL": &p.AccessTTL, "LOCAL_AUTH_REFRESH_TTL": &p.RefreshTTL} {
+		raw := os.Getenv(env)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s inválido: %q", env, raw)
+		}
+		*ttl = d
+	}
+	return p, nil
+}
+
+func (p *LocalIdentity) ValidateToken(tokenString string) (string, error) {
+	return parseHS256Token(tokenString, p.JWTSecret,
+		jwt.WithAudience("authenticated"), jwt.WithIssuer(localTokenIssuer), jwt.WithLeeway(p.Leeway))
+}
+
+const localUserColumns = `id, email, COALESCE(username, email), avatar_url, created_at, role, is_admin`
+
+func scanLocalUser(row pgx.Row, user *User) error {
+	return row.Scan(&user.ID, &user.Email, &user.Username, &user.Avatar, &user.CreatedAt, &user.Role, &user.IsAdmin)
+}
+
+func (p *LocalIdentity) GetUser(ctx context.Context, userID string) (User, error) {
+	var user User
+	err := scanLocalUser(p.db.QueryRow(ctx, "SELECT "+localUserColumns+" FROM local_users WHERE id = $1", userID), &user)
+	return user, notFound(err)
+}
+
+func (p *LocalIdentity) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := p.db.Query(ctx, "SELECT "+localUserColumns+" FROM local_users ORDER BY email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]User, 0)
+	for rows.Next() {
+		var user User
+		if err := scanLocalUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (p *LocalIdentity) UserIDByUsername(ctx context.Context, username string) (string, error) {
+	var userID string
+	err := p.db.QueryRow(ctx, "SELECT id FROM local_users WHERE username = $1 OR email = $1 LIMIT 1", username).Scan(&userID)
+	return userID, notFound(err)
+}
+
+// cadastrar usuario com senha (bcrypt)
+func (p *LocalIdentity) CreateUser(ctx context.Context, email, username, password string, isAdmin bool) (User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback(ctx)
+	var user User
+	query := `INSERT INTO local_users (email, username, password_hash, is_admin)
+	          VALUES ($1, NULLIF($2, ''), $3, $4)
+	          RETURNING ` + localUserColumns
+	if err := scanLocalUser(tx.QueryRow(ctx, query, email, username, string(hash), isAdmin), &user); err != nil {
+		return User{}, err
+	}
+	// as permissoes vem dos papeis; o flag sozinho nao da acesso
+	if isAdmin {
+		if _, err := tx.Exec(ctx, "INSERT INTO user_roles (user_id, role) VALUES ($1, 'admin') ON CONFLICT DO NOTHING", user.ID); err != nil {
+			return User{}, err
+		}
+	}
+	return user, tx.Commit(ctx)
+}
+
+// login por email ou username
+func (p *LocalIdentity) Login(ctx context.Context, login, password string) (AuthSession, error) {
+	var user User
+	var hash string
+	query := "SELECT " + localUserColumns + ", password_hash FROM local_users WHERE email = $1 OR username = $1 LIMIT 1"
+	err := p.db.QueryRow(ctx, query, login).Scan(&user.ID, &user.Email, &user.Username, &user.Avatar,
+		&user.CreatedAt, &user.Role, &user.IsAdmin, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return AuthSession{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return AuthSession{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return AuthSession{}, ErrInvalidCredentials
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return AuthSession{}, err
+	}
+	defer tx.Rollback(ctx)
+	session, err := p.newSession(ctx, tx, user)
+	if err != nil {
+		return AuthSession{}, err
+	}
+	return session, tx.Commit(ctx)
+}
+
+// trocar um refresh token valido por um novo par; o antigo fica revogado
+func (p *LocalIdentity) Refresh(ctx context.Context, refreshToken string) (AuthSession, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return AuthSession{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	query := `UPDATE local_refresh_tokens SET revoked_at = NOW()
+	          WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	          RETURNING user_id`
+	if err := tx.QueryRow(ctx, query, hashRefreshToken(refreshToken)).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthSession{}, ErrInvalidRefreshToken
+		}
+		return AuthSession{}, err
+	}
+	var user User
+	if err := scanLocalUser(tx.QueryRow(ctx, "SELECT "+localUserColumns+" FROM local_users WHERE id = $1", userID), &user); err != nil {
+		return AuthSession{}, err
+	}
+	session, err := p.newSession(ctx, tx, user)
+	if err != nil {
+		return AuthSession{}, err
+	}
+	return session, tx.Commit(ctx)
+}
+
+// access token assinado + refresh token aleatorio gravado como hash
+func (p *LocalIdentity) newSession(ctx context.Context, tx pgx.Tx, user User) (AuthSession, error) {
+	if p.JWTSecret == "" {
+		return AuthSession{}, ErrAuthConfig
+	}
+	now := time.Now()
+	claims := SupabaseClaims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    localTokenIssuer,
+			Audience:  jwt.ClaimStrings{"authenticated"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(p.AccessTTL)),
+		},
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(p.JWTSecret))
+	if err != nil {
+		return AuthSession{}, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return AuthSession{}, err
+	}
+	refreshToken := hex.EncodeToString(raw)
+	_, err = tx.Exec(ctx,
+		"INSERT INTO local_refresh_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		hashRefreshToken(refreshToken), user.ID, now.Add(p.RefreshTTL))
+	if err != nil {
+		return AuthSession{}, err
+	}
+	return AuthSession{
+		AccessToken:  accessToken,
+		TokenType:    "bearer",
+		ExpiresIn:    int(p.AccessTTL.Seconds()),
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// provedor atual, se ele aceitar login com senha
+func (app *App) credentialsProvider(c *fiber.Ctx) (CredentialsProvider, bool, error) {
+	provider, ok := app.identity.(CredentialsProvider)
+	if !ok {
+		return nil, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Login com senha não disponível neste provedor de identidade"})
+	}
+	return provider, true, nil
+}
+
+// resposta de erro comum a login e refresh
+func authSessionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidRefreshToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrAuthConfig):
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao autenticar"})
+	}
+}
+
+// endpoint login
+func (app *App) login(c *fiber.Ctx) error {
+	provider, ok, resp := app.credentialsProvider(c)
+	if !ok {
+		return resp
+	}
+	var payload struct {
+		Login    string `json:"login"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	login := payload.Login
+	if login == "" {
+		login = payload.Email
+	}
+	if login == "" {
+		login = payload.Username
+	}
+	if login == "" || payload.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Login e senha são obrigatórios"})
+	}
+	session, err := provider.Login(c.Context(), strings.TrimSpace(login), payload.Password)
+	if err != nil {
+		return authSessionError(c, err)
+	}
+	session.User = app.withProfiles(c.Context(), []User{session.User})[0]
+	return c.JSON(session)
+}
+
+// endpoint refresh
+func (app *App) refreshSession(c *fiber.Ctx) error {
+	provider, ok, resp := app.credentialsProvider(c)
+	if !ok {
+		return resp
+	}
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&payload); err != nil || payload.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token é obrigatório"})
+	}
+	session, err := provider.Refresh(c.Context(), payload.RefreshToken)
+	if err != nil {
+		return authSessionError(c, err)
+	}
+	session.User = app.withProfiles(c.Context(), []User{session.User})[0]
+	return c.JSON(session)
+}
+
+// comando: user add <email> <username> [admin]; senha lida da entrada padrao
+func (app *App) runUserCommand(args []string) error {
+	local, ok := app.identity.(*LocalIdentity)
+	if !ok {
+		return errors.New("o comando user exige AUTH_PROVIDER=local")
+	}
+	if len(args) < 3 || args[0] != "add" {
+		return errors.New("uso: user add <email> <username> [admin]")
+	}
+	isAdmin := len(args) > 3 && args[3] == "admin"
+
+	fmt.Fprint(os.Stderr, "Senha: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("ler senha: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < 8 {
+		return errors.New("a senha precisa ter pelo menos 8 caracteres")
+	}
+
+	user, err := local.CreateUser(context.Background(), args[1], args[2], password, isAdmin)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("email ou username já cadastrado")
+		}
+		return err
+	}
+	fmt.Printf("usuário criado: %s (%s)\n", user.ID, user.Email)
+	return nil
+}
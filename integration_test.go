@@ -0,0 +1,1204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// board privado com as colunas padrao, criado pela API
+func (e *testEnv) createBoard(ownerID, title string) (Board, []Column) {
+	e.t.Helper()
+	var board Board
+	e.expect(http.StatusCreated, "POST", "/api/boards", ownerID, fiber.Map{"title": title}, &board)
+	return board, e.columns(board.ID, ownerID)
+}
+
+func (e *testEnv) columns(boardID int, userID string) []Column {
+	e.t.Helper()
+	var columns []Column
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/boards/%d/columns", boardID), userID, nil, &columns)
+	return columns
+}
+
+func (e *testEnv) createCard(columnID int, userID, title string) Card {
+	e.t.Helper()
+	var card Card
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/columns/%d/cards", columnID), userID, fiber.Map{"title": title}, &card)
+	return card
+}
+
+func (e *testEnv) cards(columnID int, userID string) []Card {
+	e.t.Helper()
+	var cards []Card
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/columns/%d/cards", columnID), userID, nil, &cards)
+	return cards
+}
+
+func (e *testEnv) moveCard(userID string, cardID, columnID, position int) {
+	e.t.Helper()
+	e.expect(http.StatusNoContent, "POST", "/api/cards/move", userID,
+		fiber.Map{"card_id": cardID, "new_column_id": columnID, "new_position": position}, nil)
+}
+
+func cardTitles(cards []Card) []string {
+	titles := make([]string, len(cards))
+	for i, c := range cards {
+		titles[i] = c.Title
+	}
+	return titles
+}
+
+// posicoes precisam continuar contiguas depois de cada movimento
+func assertColumn(t *testing.T, cards []Card, titles ...string) {
+	t.Helper()
+	if got := cardTitles(cards); len(got) != len(titles) || (len(got) > 0 && !reflect.DeepEqual(got, titles)) {
+		t.Fatalf("cards da coluna = %v, esperado %v", got, titles)
+	}
+	for i := 1; i < len(cards); i++ {
+		if cards[i].Position != cards[i-1].Position+1 {
+			t.Fatalf("posições não contíguas: %v", cards)
+		}
+	}
+}
+
+func TestMoveCardBetweenAndWithinColumns(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	_, cols := e.createBoard(owner, "Suporte")
+	todo, doing := cols[0].ID, cols[1].ID
+
+	a := e.createCard(todo, owner, "A")
+	b := e.createCard(todo, owner, "B")
+	c := e.createCard(todo, owner, "C")
+	assertColumn(t, e.cards(todo, owner), "A", "B", "C")
+
+	// para outra coluna, no topo
+	e.moveCard(owner, c.ID, doing, 0)
+	assertColumn(t, e.cards(todo, owner), "A", "B")
+	assertColumn(t, e.cards(doing, owner), "C")
+
+	// de volta para a primeira coluna, no lugar de B
+	e.moveCard(owner, c.ID, todo, b.Position)
+	assertColumn(t, e.cards(todo, owner), "A", "C", "B")
+	assertColumn(t, e.cards(doing, owner))
+
+	// dentro da mesma coluna: A vai para onde C esta
+	e.moveCard(owner, a.ID, todo, e.cards(todo, owner)[1].Position)
+	assertColumn(t, e.cards(todo, owner), "C", "A", "B")
+}
+
+func TestMoveCardAcrossBoardsNotifiesBoth(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	source, sourceColumns := e.createBoard(owner, "Origem")
+	target, targetColumns := e.createBoard(owner, "Destino")
+	card := e.createCard(sourceColumns[0].ID, owner, "Viajante")
+
+	e.moveCard(owner, card.ID, targetColumns[0].ID, 0)
+	assertColumn(t, e.cards(sourceColumns[0].ID, owner))
+	assertColumn(t, e.cards(targetColumns[0].ID, owner), "Viajante")
+
+	for _, boardID := range []int{source.ID, target.ID} {
+		var moves int
+		e.app.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM board_events WHERE board_id = $1 AND type = 'CARD_MOVED'", boardID).Scan(&moves)
+		if moves != 1 {
+			t.Fatalf("board %d deveria ter um CARD_MOVED no backlog: %d", boardID, moves)
+		}
+	}
+}
+
+func TestMoveCardCompletionColumn(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	board, cols := e.createBoard(owner, "Suporte")
+
+	// a conclusao vem da categoria, nao do titulo
+	var done Column
+	e.expect(http.StatusCreated, "POST", "/api/columns", owner,
+		fiber.Map{"board_id": board.ID, "title": "Resolvido", "category": "done_success"}, &done)
+	card := e.createCard(cols[0].ID, owner, "Chamado")
+
+	e.moveCard(owner, card.ID, done.ID, 0)
+	moved := e.cards(done.ID, owner)
+	if len(moved) != 1 || moved[0].CompletedAt == nil {
+		t.Fatalf("card movido para coluna done_success deveria ter completed_at: %+v", moved)
+	}
+
+	e.moveCard(owner, card.ID, cols[1].ID, 0)
+	back := e.cards(cols[1].ID, owner)
+	if len(back) != 1 || back[0].CompletedAt != nil {
+		t.Fatalf("card que saiu da coluna done_success deveria perder completed_at: %+v", back)
+	}
+}
+
+func TestColumnCategories(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	board, cols := e.createBoard(owner, "Suporte")
+
+	want := []ColumnCategory{CategoryBacklog, CategoryInProgress, CategoryDoneSuccess}
+	for i, col := range cols {
+		if col.Category != want[i] {
+			t.Fatalf("coluna %q deveria ser %s, veio %s", col.Title, want[i], col.Category)
+		}
+	}
+
+	e.expect(http.StatusBadRequest, "POST", "/api/columns", owner,
+		fiber.Map{"board_id": board.ID, "title": "X", "category": "talvez"}, nil)
+
+	// renomear nao muda a categoria
+	var renamed Column
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/columns/%d", cols[2].ID), owner,
+		fiber.Map{"title": "Entregue", "color": "#3fb950"}, &renamed)
+	if renamed.Category != CategoryDoneSuccess {
+		t.Fatalf("renomear a coluna nao deveria mudar a categoria: %+v", renamed)
+	}
+	card := e.createCard(cols[0].ID, owner, "Chamado")
+	e.moveCard(owner, card.ID, cols[2].ID, 0)
+	if moved := e.cards(cols[2].ID, owner); len(moved) != 1 || moved[0].CompletedAt == nil {
+		t.Fatalf("card em coluna renomeada deveria continuar concluido: %+v", moved)
+	}
+
+	// o PUT nao escreve completed_at: editar um card concluido mantem, e o cliente nao conclui fora da coluna
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/cards/%d", card.ID), owner, fiber.Map{"title": "Chamado editado"}, nil)
+	if moved := e.cards(cols[2].ID, owner); len(moved) != 1 || moved[0].CompletedAt == nil {
+		t.Fatalf("editar o card nao deveria reabri-lo: %+v", moved)
+	}
+	open := e.createCard(cols[0].ID, owner, "Aberto")
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/cards/%d", open.ID), owner,
+		fiber.Map{"title": "Aberto", "completed_at": time.Now()}, nil)
+	if got := e.cards(cols[0].ID, owner); len(got) != 1 || got[0].CompletedAt != nil {
+		t.Fatalf("completed_at enviado pelo cliente deveria ser ignorado: %+v", got)
+	}
+
+	// criar direto numa coluna de conclusao
+	direct := e.createCard(cols[2].ID, owner, "Ja feito")
+	if direct.CompletedAt == nil {
+		t.Fatalf("card criado em coluna done deveria nascer concluido: %+v", direct)
+	}
+
+	// quadro publico usa o template de atendimento, sem criar colunas na leitura
+	var public Board
+	e.expect(http.StatusCreated, "POST", "/api/boards", owner, fiber.Map{"title": "Publico", "is_public": true}, &public)
+	first := e.columns(public.ID, owner)
+	if len(first) != 4 || first[3].Category != CategoryDoneFailure {
+		t.Fatalf("quadro publico deveria ter 4 colunas do template atendimento: %+v", first)
+	}
+	if again := e.columns(public.ID, owner); len(again) != len(first) {
+		t.Fatalf("ler as colunas nao deveria criar colunas: %d -> %d", len(first), len(again))
+	}
+
+	e.expect(http.StatusBadRequest, "POST", "/api/boards", owner, fiber.Map{"title": "X", "template": "nenhum"}, nil)
+}
+
+func TestMoveCardUnknownTarget(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	_, cols := e.createBoard(owner, "Suporte")
+	card := e.createCard(cols[0].ID, owner, "A")
+
+	e.expect(http.StatusNotFound, "POST", "/api/cards/move", owner,
+		fiber.Map{"card_id": card.ID, "new_column_id": 999999, "new_position": 0}, nil)
+	e.expect(http.StatusNotFound, "POST", "/api/cards/move", owner,
+		fiber.Map{"card_id": 999999, "new_column_id": cols[0].ID, "new_position": 0}, nil)
+	assertColumn(t, e.cards(cols[0].ID, owner), "A")
+}
+
+func TestReorderColumns(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	outsider := e.createUser("intruso", false)
+	board, cols := e.createBoard(owner, "Suporte")
+
+	order := []int{cols[2].ID, cols[0].ID, cols[1].ID}
+	path := fmt.Sprintf("/api/boards/%d/columns/reorder", board.ID)
+	e.expect(http.StatusNoContent, "POST", path, owner, fiber.Map{"ordered_column_ids": order}, nil)
+
+	reordered := e.columns(board.ID, owner)
+	for i, col := range reordered {
+		if col.ID != order[i] || col.Position != i {
+			t.Fatalf("colunas após reordenar = %+v, esperado ids %v", reordered, order)
+		}
+	}
+
+	e.expect(http.StatusForbidden, "POST", path, outsider, fiber.Map{"ordered_column_ids": []int{cols[0].ID}}, nil)
+	if after := e.columns(board.ID, owner); after[0].ID != order[0] {
+		t.Fatalf("usuário sem acesso conseguiu reordenar: %+v", after)
+	}
+}
+
+// convite pendente mais recente do usuario
+func (e *testEnv) pendingInvitation(userID string) Notification {
+	e.t.Helper()
+	var notifications []Notification
+	e.expect(http.StatusOK, "GET", "/api/notifications", userID, nil, &notifications)
+	for _, n := range notifications {
+		if n.Type == "board_invitation" && n.InvitationStatus == "pending" && n.InvitationID != nil {
+			return n
+		}
+	}
+	e.t.Fatalf("nenhum convite pendente para %s: %+v", userID, notifications)
+	return Notification{}
+}
+
+func TestInvitationAccept(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	guest := e.createUser("convidado", false)
+	board, _ := e.createBoard(owner, "Projeto")
+	columnsPath := fmt.Sprintf("/api/boards/%d/columns", board.ID)
+
+	e.expect(http.StatusForbidden, "GET", columnsPath, guest, nil, nil)
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), owner, fiber.Map{"invitee_id": guest}, nil)
+
+	invite := e.pendingInvitation(guest)
+	if invite.RelatedBoardID == nil || *invite.RelatedBoardID != board.ID {
+		t.Fatalf("convite aponta para o board errado: %+v", invite)
+	}
+	respondPath := fmt.Sprintf("/api/invitations/%d/respond?notification_id=%d", *invite.InvitationID, invite.ID)
+	e.expect(http.StatusOK, "POST", respondPath, guest, fiber.Map{"accept": true}, nil)
+
+	e.expect(http.StatusOK, "GET", columnsPath, guest, nil, nil)
+
+	var private []Board
+	e.expect(http.StatusOK, "GET", "/api/boards/private", guest, nil, &private)
+	if len(private) != 1 || private[0].ID != board.ID {
+		t.Fatalf("board compartilhado não aparece para o convidado: %+v", private)
+	}
+
+	var ownerNotifications []Notification
+	e.expect(http.StatusOK, "GET", "/api/notifications", owner, nil, &ownerNotifications)
+	accepted := false
+	for _, n := range ownerNotifications {
+		accepted = accepted || n.Type == "invitation_accepted"
+	}
+	if !accepted {
+		t.Fatalf("dono não foi notificado do aceite: %+v", ownerNotifications)
+	}
+
+	var guestNotifications []Notification
+	e.expect(http.StatusOK, "GET", "/api/notifications", guest, nil, &guestNotifications)
+	for _, n := range guestNotifications {
+		if n.ID == invite.ID && !n.IsRead {
+			t.Fatalf("notificação do convite deveria estar lida: %+v", n)
+		}
+	}
+
+	// responder de novo nao e permitido
+	e.expect(http.StatusNotFound, "POST", respondPath, guest, fiber.Map{"accept": true}, nil)
+}
+
+func TestInvitationReject(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	guest := e.createUser("convidado", false)
+	other := e.createUser("outro", false)
+	board, _ := e.createBoard(owner, "Projeto")
+
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), owner, fiber.Map{"invitee_id": guest}, nil)
+	invite := e.pendingInvitation(guest)
+	respondPath := fmt.Sprintf("/api/invitations/%d/respond", *invite.InvitationID)
+
+	// so o convidado pode responder
+	e.expect(http.StatusNotFound, "POST", respondPath, other, fiber.Map{"accept": true}, nil)
+
+	e.expect(http.StatusOK, "POST", respondPath, guest, fiber.Map{"accept": false}, nil)
+	e.expect(http.StatusForbidden, "GET", fmt.Sprintf("/api/boards/%d/columns", board.ID), guest, nil, nil)
+
+	// convidar de novo reabre o convite
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), owner, fiber.Map{"invitee_id": guest}, nil)
+	if again := e.pendingInvitation(guest); *again.InvitationID != *invite.InvitationID {
+		t.Fatalf("reconvite deveria reaproveitar o convite %d, veio %d", *invite.InvitationID, *again.InvitationID)
+	}
+}
+
+func contatoAssignee(t *testing.T, e *testEnv, userID, contatoID string) *string {
+	t.Helper()
+	var statuses []ContatoStatus
+	e.expect(http.StatusOK, "GET", "/api/contatos/status", userID, nil, &statuses)
+	for _, cs := range statuses {
+		if cs.ContatoID == contatoID {
+			return cs.AssignedTo
+		}
+	}
+	t.Fatalf("contato %s não encontrado", contatoID)
+	return nil
+}
+
+func TestUnassignContatoRules(t *testing.T) {
+	e := newTestEnv(t)
+	tecnico := e.createUser("tecnico", false)
+	colega := e.createUser("colega", false)
+	admin := e.createUser("admin", true)
+
+	e.expect(http.StatusOK, "POST", "/api/contatos/assign", tecnico, fiber.Map{"contato_id": "cliente-1"}, nil)
+	if got := contatoAssignee(t, e, tecnico, "cliente-1"); got == nil || *got != tecnico {
+		t.Fatalf("contato deveria estar com o técnico, está com %v", got)
+	}
+
+	e.expect(http.StatusBadRequest, "POST", "/api/contatos/unassign", tecnico, fiber.Map{}, nil)
+	e.expect(http.StatusNotFound, "POST", "/api/contatos/unassign", tecnico, fiber.Map{"contato_id": "nao-existe"}, nil)
+
+	// outro usuario comum nao pode liberar o contato de alguem
+	e.expect(http.StatusForbidden, "POST", "/api/contatos/unassign", colega, fiber.Map{"contato_id": "cliente-1"}, nil)
+	if got := contatoAssignee(t, e, tecnico, "cliente-1"); got == nil || *got != tecnico {
+		t.Fatalf("contato não deveria ter mudado, está com %v", got)
+	}
+
+	// o proprio responsavel pode
+	e.expect(http.StatusOK, "POST", "/api/contatos/unassign", tecnico, fiber.Map{"contato_id": "cliente-1"}, nil)
+	if got := contatoAssignee(t, e, tecnico, "cliente-1"); got != nil {
+		t.Fatalf("contato deveria estar livre, está com %s", *got)
+	}
+
+	// contato livre tambem nao pode ser "liberado" por quem nao e admin
+	e.expect(http.StatusForbidden, "POST", "/api/contatos/unassign", tecnico, fiber.Map{"contato_id": "cliente-1"}, nil)
+
+	// admin libera de qualquer um
+	e.expect(http.StatusOK, "POST", "/api/contatos/assign", colega, fiber.Map{"contato_id": "cliente-1"}, nil)
+	e.expect(http.StatusOK, "POST", "/api/contatos/unassign", admin, fiber.Map{"contato_id": "cliente-1"}, nil)
+	if got := contatoAssignee(t, e, admin, "cliente-1"); got != nil {
+		t.Fatalf("admin deveria ter liberado o contato, está com %s", *got)
+	}
+}
+
+func TestLocalAuthLoginAndRefresh(t *testing.T) {
+	e := newTestEnv(t)
+	local := &LocalIdentity{db: e.app.db, JWTSecret: "segredo-local-de-teste", AccessTTL: time.Minute, RefreshTTL: time.Hour}
+	e.app.useIdentity(local)
+	user, err := local.CreateUser(context.Background(), "ana@teste.local", "ana", "senha-forte-123", false)
+	if err != nil {
+		t.Fatalf("criar usuário local: %v", err)
+	}
+
+	status, _ := e.send("POST", "/api/auth/login", "", fiber.Map{"login": "ana", "password": "errada"})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("senha errada: status %d, esperado 401", status)
+	}
+	status, _ = e.send("POST", "/api/auth/login", "", fiber.Map{"login": "ninguem", "password": "errada"})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("usuário inexistente: status %d, esperado 401", status)
+	}
+
+	var session AuthSession
+	status, raw := e.send("POST", "/api/auth/login", "", fiber.Map{"email": "ana@teste.local", "password": "senha-forte-123"})
+	if status != http.StatusOK || json.Unmarshal(raw, &session) != nil {
+		t.Fatalf("login: status %d: %s", status, raw)
+	}
+	if session.User.ID != user.ID || session.AccessToken == "" || session.RefreshToken == "" {
+		t.Fatalf("sessão incompleta: %+v", session)
+	}
+
+	// o access token vale nas rotas protegidas e os usuarios vem de local_users
+	var users []User
+	status, raw = e.send("GET", "/api/users", session.AccessToken, nil)
+	if status != http.StatusOK || json.Unmarshal(raw, &users) != nil || len(users) != 1 || users[0].Username != "ana" {
+		t.Fatalf("GET /api/users com token local: status %d: %s", status, raw)
+	}
+
+	// refresh gira o token: o antigo deixa de valer
+	var refreshed AuthSession
+	status, raw = e.send("POST", "/api/auth/refresh", "", fiber.Map{"refresh_token": session.RefreshToken})
+	if status != http.StatusOK || json.Unmarshal(raw, &refreshed) != nil {
+		t.Fatalf("refresh: status %d: %s", status, raw)
+	}
+	if refreshed.RefreshToken == session.RefreshToken {
+		t.Fatal("refresh deveria emitir um novo refresh token")
+	}
+	status, _ = e.send("POST", "/api/auth/refresh", "", fiber.Map{"refresh_token": session.RefreshToken})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("refresh token reutilizado: status %d, esperado 401", status)
+	}
+
+	// token do Supabase nao vale no provedor local
+	status, _ = e.request("GET", "/api/users", user.ID, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("token de outro emissor: status %d, esperado 401", status)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	e := newTestEnv(t)
+	ana := e.createUser("ana", false)
+	bia := e.createUser("bia", false)
+	admin := e.createUser("admin", true)
+
+	var profile map[string]interface{}
+	e.expect(http.StatusOK, "GET", "/api/users/me/profile", ana, nil, &profile)
+	if profile["resolved_name"] != "ana" || profile["active"] != true {
+		t.Fatalf("perfil padrão inesperado: %v", profile)
+	}
+
+	e.expect(http.StatusOK, "PUT", "/api/users/me/profile", ana, fiber.Map{"display_name": " Ana Souza ", "team": "Suporte"}, &profile)
+	if profile["display_name"] != "Ana Souza" || profile["resolved_name"] != "Ana Souza" || profile["team"] != "Suporte" {
+		t.Fatalf("perfil não atualizado: %v", profile)
+	}
+	e.expect(http.StatusForbidden, "PUT", "/api/users/me/profile", ana, fiber.Map{"active": false}, nil)
+	e.expect(http.StatusForbidden, "PUT", "/api/users/"+bia+"/profile", ana, fiber.Map{"display_name": "x"}, nil)
+
+	// o nome do perfil aparece para quem recebe um board compartilhado
+	board, _ := e.createBoard(ana, "Quadro da Ana")
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), ana, fiber.Map{"invitee_id": bia}, nil)
+	invite := e.pendingInvitation(bia)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/invitations/%d/respond?notification_id=%d", *invite.InvitationID, invite.ID), bia, fiber.Map{"accept": true}, nil)
+	var boards []Board
+	e.expect(http.StatusOK, "GET", "/api/boards/private", bia, nil, &boards)
+	if len(boards) != 1 || boards[0].OwnerName != "Ana Souza" {
+		t.Fatalf("owner_name deveria vir do perfil: %+v", boards)
+	}
+
+	// admin desativa a bia: continua listada, mas nao pode mais ser convidada
+	e.expect(http.StatusOK, "PUT", "/api/users/"+bia+"/profile", admin, fiber.Map{"active": false, "display_name": "Bia Lima"}, nil)
+	var users []User
+	e.expect(http.StatusOK, "GET", "/api/users", admin, nil, &users)
+	for _, u := range users {
+		if u.ID == bia && (u.Active || u.DisplayName != "Bia Lima") {
+			t.Fatalf("bia deveria estar inativa e com nome do perfil: %+v", u)
+		}
+	}
+	other, _ := e.createBoard(admin, "Quadro do admin")
+	var invitable []User
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/boards/%d/invitable-users", other.ID), admin, nil, &invitable)
+	for _, u := range invitable {
+		if u.ID == bia {
+			t.Fatal("usuário inativo não deveria ser convidável")
+		}
+	}
+	e.expect(http.StatusNotFound, "GET", "/api/users/00000000-0000-0000-0000-000000000000/profile", admin, nil, nil)
+}
+
+func TestRolesAndPermissions(t *testing.T) {
+	e := newTestEnv(t)
+	admin := e.createUser("admin", true)
+	sup := e.createUser("supervisora", false)
+	tec := e.createUser("tecnico", false)
+
+	assign := fiber.Map{"contato_id": "cliente-7", "assignee_id": tec}
+	e.expect(http.StatusForbidden, "POST", "/api/contatos/admin-assign", sup, assign, nil)
+	e.expect(http.StatusForbidden, "PUT", "/api/users/"+sup+"/roles", sup, fiber.Map{"roles": []string{"admin"}}, nil)
+
+	var granted struct {
+		Roles       []string `json:"roles"`
+		Permissions []string `json:"permissions"`
+	}
+	e.expect(http.StatusOK, "PUT", "/api/users/"+sup+"/roles", admin, fiber.Map{"roles": []string{"supervisor"}}, &granted)
+	if !reflect.DeepEqual(granted.Roles, []string{"supervisor"}) {
+		t.Fatalf("papéis atribuídos: %v", granted.Roles)
+	}
+	e.expect(http.StatusBadRequest, "PUT", "/api/users/"+sup+"/roles", admin, fiber.Map{"roles": []string{"dono-do-mundo"}}, nil)
+
+	// supervisora distribui contatos e libera o de outra pessoa, mas nao gerencia usuarios
+	e.expect(http.StatusOK, "POST", "/api/contatos/admin-assign", sup, assign, nil)
+	e.expect(http.StatusOK, "POST", "/api/contatos/unassign", sup, fiber.Map{"contato_id": "cliente-7"}, nil)
+	e.expect(http.StatusForbidden, "GET", "/api/roles", sup, nil, nil)
+
+	var mine struct {
+		Permissions []string `json:"permissions"`
+	}
+	e.expect(http.StatusOK, "GET", "/api/users/me/permissions", tec, nil, &mine)
+	if len(mine.Permissions) != 0 {
+		t.Fatalf("técnico sem papéis não deveria ter permissões: %v", mine.Permissions)
+	}
+
+	// quem gerencia usuarios so pelo papel nao pode tirar o proprio papel
+	e.expect(http.StatusOK, "PUT", "/api/users/"+sup+"/roles", admin, fiber.Map{"roles": []string{"admin"}}, nil)
+	e.expect(http.StatusBadRequest, "PUT", "/api/users/"+sup+"/roles", sup, fiber.Map{"roles": []string{}}, nil)
+	e.expect(http.StatusOK, "PUT", "/api/users/"+tec+"/roles", sup, fiber.Map{"roles": []string{"tecnico"}}, nil)
+
+	// admin pelo flag legado perde o acesso quando perde o papel
+	chefe := e.createUser("chefe", true)
+	e.expect(http.StatusOK, "GET", "/api/roles", chefe, nil, nil)
+	e.expect(http.StatusOK, "PUT", "/api/users/"+chefe+"/roles", admin, fiber.Map{"roles": []string{}}, nil)
+	e.expect(http.StatusForbidden, "GET", "/api/roles", chefe, nil, nil)
+	e.expect(http.StatusOK, "GET", "/api/users/me/permissions", chefe, nil, &mine)
+	if len(mine.Permissions) != 0 {
+		t.Fatalf("admin rebaixado não deveria ter permissões: %v", mine.Permissions)
+	}
+}
+
+// convite com papel, aceito pelo convidado
+func (e *testEnv) addMember(ownerID string, boardID int, userID, role string) {
+	e.t.Helper()
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/invite", boardID), ownerID, fiber.Map{"invitee_id": userID, "role": role}, nil)
+	invite := e.pendingInvitation(userID)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/invitations/%d/respond", *invite.InvitationID), userID, fiber.Map{"accept": true}, nil)
+}
+
+func TestBoardMemberRoles(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	viewer := e.createUser("leitor", false)
+	commenter := e.createUser("comentador", false)
+	editor := e.createUser("editor", false)
+	board, columns := e.createBoard(owner, "Projeto")
+	e.addMember(owner, board.ID, viewer, "viewer")
+	e.addMember(owner, board.ID, commenter, "commenter")
+	e.addMember(owner, board.ID, editor, "")
+
+	e.expect(http.StatusBadRequest, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), owner, fiber.Map{"invitee_id": viewer, "role": "owner"}, nil)
+
+	var members []struct {
+		ID   string    `json:"id"`
+		Role BoardRole `json:"role"`
+	}
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/boards/%d/members", board.ID), viewer, nil, &members)
+	roles := map[string]BoardRole{}
+	for _, m := range members {
+		roles[m.ID] = m.Role
+	}
+	want := map[string]BoardRole{owner: BoardRoleOwner, viewer: BoardRoleViewer, commenter: BoardRoleCommenter, editor: BoardRoleEditor}
+	if !reflect.DeepEqual(roles, want) {
+		t.Fatalf("papéis dos membros = %v, esperado %v", roles, want)
+	}
+
+	card := e.createCard(columns[0].ID, editor, "Tarefa")
+	cardsPath := fmt.Sprintf("/api/columns/%d/cards", columns[0].ID)
+	commentsPath := fmt.Sprintf("/api/cards/%d/comments", card.ID)
+	move := fiber.Map{"card_id": card.ID, "new_column_id": columns[1].ID, "new_position": 0}
+
+	// leitor so le
+	e.expect(http.StatusOK, "GET", cardsPath, viewer, nil, nil)
+	e.expect(http.StatusForbidden, "POST", cardsPath, viewer, fiber.Map{"title": "Nova"}, nil)
+	e.expect(http.StatusForbidden, "POST", commentsPath, viewer, fiber.Map{"text": "oi"}, nil)
+
+	// comentador comenta, mas nao move
+	e.expect(http.StatusCreated, "POST", commentsPath, commenter, fiber.Map{"text": "oi"}, nil)
+	e.expect(http.StatusForbidden, "POST", "/api/cards/move", commenter, move, nil)
+	e.expect(http.StatusForbidden, "PUT", fmt.Sprintf("/api/cards/%d", card.ID), commenter, fiber.Map{"title": "Outro"}, nil)
+
+	// so o dono convida e muda papeis
+	e.expect(http.StatusForbidden, "PUT", fmt.Sprintf("/api/boards/%d/members/%s/role", board.ID, viewer), editor, fiber.Map{"role": "editor"}, nil)
+	e.expect(http.StatusForbidden, "POST", fmt.Sprintf("/api/boards/%d/invite", board.ID), editor, fiber.Map{"invitee_id": owner}, nil)
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/boards/%d/members/%s/role", board.ID, commenter), owner, fiber.Map{"role": "editor"}, nil)
+	e.moveCard(commenter, card.ID, columns[1].ID, 0)
+
+	// transferencia: o novo dono precisa ser membro, e o antigo vira editor e pode sair
+	outsider := e.createUser("fora", false)
+	transferPath := fmt.Sprintf("/api/boards/%d/transfer", board.ID)
+	e.expect(http.StatusBadRequest, "POST", transferPath, owner, fiber.Map{"new_owner_id": outsider}, nil)
+	e.expect(http.StatusForbidden, "POST", fmt.Sprintf("/api/boards/%d/leave", board.ID), owner, nil, nil)
+	e.expect(http.StatusOK, "POST", transferPath, owner, fiber.Map{"new_owner_id": editor}, nil)
+	e.expect(http.StatusForbidden, "PUT", fmt.Sprintf("/api/boards/%d/members/%s/role", board.ID, viewer), owner, fiber.Map{"role": "editor"}, nil)
+	e.expect(http.StatusNoContent, "POST", fmt.Sprintf("/api/boards/%d/leave", board.ID), owner, nil, nil)
+	e.expect(http.StatusForbidden, "GET", cardsPath, owner, nil, nil)
+}
+
+func (e *testEnv) trash(userID string) []TrashItem {
+	e.t.Helper()
+	var items []TrashItem
+	e.expect(http.StatusOK, "GET", "/api/trash", userID, nil, &items)
+	return items
+}
+
+func TestTrashRestore(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	other := e.createUser("outro", false)
+	board, columns := e.createBoard(owner, "Projeto")
+	first := columns[0].ID
+	for _, title := range []string{"A", "B", "C"} {
+		e.createCard(first, owner, title)
+	}
+	cards := e.cards(first, owner)
+
+	// card excluido sai da coluna sem deixar buraco e volta para o mesmo lugar
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/cards/%d", cards[1].ID), owner, nil, nil)
+	assertColumn(t, e.cards(first, owner), "A", "C")
+	if items := e.trash(owner); len(items) != 1 || items[0].Type != "card" || items[0].ID != cards[1].ID || items[0].ColumnID == nil || *items[0].ColumnID != first {
+		t.Fatalf("lixeira = %+v", items)
+	}
+	if items := e.trash(other); len(items) != 0 {
+		t.Fatalf("lixeira de outro usuário deveria estar vazia: %+v", items)
+	}
+	e.expect(http.StatusForbidden, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[1].ID), other, nil, nil)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[1].ID), owner, nil, nil)
+	assertColumn(t, e.cards(first, owner), "A", "B", "C")
+	e.expect(http.StatusNotFound, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[1].ID), owner, nil, nil)
+
+	// coluna: o card dela so volta depois da coluna
+	last := columns[len(columns)-1].ID
+	e.moveCard(owner, cards[0].ID, last, 0)
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/cards/%d", cards[0].ID), owner, nil, nil)
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/columns/%d", last), owner, nil, nil)
+	if got := e.columns(board.ID, owner); len(got) != len(columns)-1 {
+		t.Fatalf("coluna excluída ainda aparece: %+v", got)
+	}
+	e.expect(http.StatusConflict, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[0].ID), owner, nil, nil)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/trash/column/%d/restore", last), owner, nil, nil)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[0].ID), owner, nil, nil)
+	restored := e.columns(board.ID, owner)
+	if len(restored) != len(columns) || restored[len(restored)-1].ID != last {
+		t.Fatalf("coluna não voltou para a posição original: %+v", restored)
+	}
+	assertColumn(t, e.cards(last, owner), "A")
+
+	// board na lixeira some das listas e do acesso ate ser restaurado
+	e.expect(http.StatusNoContent, "DELETE", fmt.Sprintf("/api/boards/%d", board.ID), owner, nil, nil)
+	var private []Board
+	e.expect(http.StatusOK, "GET", "/api/boards/private", owner, nil, &private)
+	if len(private) != 0 {
+		t.Fatalf("board excluído ainda aparece: %+v", private)
+	}
+	e.expect(http.StatusForbidden, "GET", fmt.Sprintf("/api/boards/%d/columns", board.ID), owner, nil, nil)
+	e.expect(http.StatusForbidden, "PUT", fmt.Sprintf("/api/cards/%d", cards[2].ID), owner, fiber.Map{"title": "X"}, nil)
+	e.expect(http.StatusForbidden, "POST", fmt.Sprintf("/api/trash/board/%d/restore", board.ID), other, nil, nil)
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/trash/board/%d/restore", board.ID), owner, nil, nil)
+	assertColumn(t, e.cards(first, owner), "B", "C")
+
+	// limpeza definitiva
+	e.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/cards/%d", cards[2].ID), owner, nil, nil)
+	if _, err := e.app.purgeTrashBefore(context.Background(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("limpar lixeira: %v", err)
+	}
+	if items := e.trash(owner); len(items) != 0 {
+		t.Fatalf("lixeira deveria estar vazia depois da limpeza: %+v", items)
+	}
+	e.expect(http.StatusNotFound, "POST", fmt.Sprintf("/api/trash/card/%d/restore", cards[2].ID), owner, nil, nil)
+}
+
+func TestCardArchiving(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	board, columns := e.createBoard(owner, "Projeto")
+	first := columns[0].ID
+	for _, title := range []string{"A", "B", "C"} {
+		e.createCard(first, owner, title)
+	}
+	cards := e.cards(first, owner)
+	archivePath := fmt.Sprintf("/api/cards/%d/archive", cards[1].ID)
+
+	e.expect(http.StatusOK, "POST", archivePath, owner, nil, nil)
+	e.expect(http.StatusConflict, "POST", archivePath, owner, nil, nil)
+	assertColumn(t, e.cards(first, owner), "A", "C")
+	var all []Card
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/columns/%d/cards?include_archived=true", first), owner, nil, &all)
+	if got := cardTitles(all); !reflect.DeepEqual(got, []string{"A", "C", "B"}) || all[2].ArchivedAt == nil {
+		t.Fatalf("listagem com arquivados = %v", all)
+	}
+	e.expect(http.StatusConflict, "POST", "/api/cards/move", owner,
+		fiber.Map{"card_id": cards[1].ID, "new_column_id": first, "new_position": 0}, nil)
+
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/cards/%d/unarchive", cards[1].ID), owner, nil, nil)
+	assertColumn(t, e.cards(first, owner), "A", "C", "B")
+
+	// concluido ha mais tempo que o limite vai para o arquivo sozinho
+	if _, err := e.app.db.Exec(context.Background(), "UPDATE cards SET completed_at = NOW() - INTERVAL '40 days' WHERE id = $1", cards[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	perBoard, err := e.app.archiveCompletedBefore(context.Background(), time.Now().AddDate(0, 0, -30))
+	if err != nil || perBoard[board.ID] != 1 {
+		t.Fatalf("arquivamento automático: %v, erro %v", perBoard, err)
+	}
+	assertColumn(t, e.cards(first, owner), "C", "B")
+	var archivedBy string
+	if err := e.app.db.QueryRow(context.Background(), "SELECT archived_by::text FROM cards WHERE id = $1", cards[0].ID).Scan(&archivedBy); err != nil || archivedBy != systemActorID {
+		t.Fatalf("archived_by do arquivamento automático = %q, erro %v", archivedBy, err)
+	}
+	var activity []CardActivity
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/cards/%d/activity", cards[0].ID), owner, nil, &activity)
+	if last := activity[len(activity)-1]; last.Action != "archived" || last.ActorID != systemActorID || last.ActorName != "Sistema" {
+		t.Fatalf("histórico do arquivamento automático: %+v", last)
+	}
+
+	var bulk struct {
+		Archived int `json:"archived"`
+	}
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/columns/%d/archive", first), owner, nil, &bulk)
+	if bulk.Archived != 2 {
+		t.Fatalf("arquivamento da coluna arquivou %d cards, esperado 2", bulk.Archived)
+	}
+	assertColumn(t, e.cards(first, owner))
+	e.expect(http.StatusOK, "GET", fmt.Sprintf("/api/columns/%d/cards?include_archived=true", first), owner, nil, &all)
+	if len(all) != 3 {
+		t.Fatalf("arquivados deveriam continuar consultáveis: %v", cardTitles(all))
+	}
+}
+
+func TestColumnPolicies(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	editor := e.createUser("editor", false)
+	board, columns := e.createBoard(owner, "Projeto")
+	e.addMember(owner, board.ID, editor, "editor")
+	todo, doing := columns[0].ID, columns[1].ID
+	policyPath := fmt.Sprintf("/api/columns/%d/policy", doing)
+
+	e.expect(http.StatusForbidden, "PUT", policyPath, editor, fiber.Map{"wip_limit": 1}, nil)
+	e.expect(http.StatusBadRequest, "PUT", policyPath, owner, fiber.Map{"wip_limit": 0}, nil)
+	var col Column
+	e.expect(http.StatusOK, "PUT", policyPath, owner, fiber.Map{"wip_limit": 1, "require_assignee": true}, &col)
+	if col.WipLimit == nil || *col.WipLimit != 1 || !col.RequireAssignee {
+		t.Fatalf("política não salva: %+v", col)
+	}
+
+	var violation ColumnPolicyViolation
+	e.expect(http.StatusConflict, "POST", fmt.Sprintf("/api/columns/%d/cards", doing), editor, fiber.Map{"title": "Sem dono"}, &violation)
+	if violation.Code != "COLUMN_POLICY_VIOLATION" || violation.Rule != RuleRequireAssignee || violation.ColumnID != doing {
+		t.Fatalf("violação inesperada: %+v", violation)
+	}
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/columns/%d/cards", doing), editor, fiber.Map{"title": "Primeiro", "assigned_to": "editor"}, nil)
+
+	second := Card{}
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/columns/%d/cards", todo), editor, fiber.Map{"title": "Segundo", "assigned_to": "editor"}, &second)
+	move := fiber.Map{"card_id": second.ID, "new_column_id": doing, "new_position": 0}
+	e.expect(http.StatusConflict, "POST", "/api/cards/move", editor, move, &violation)
+	if violation.Rule != RuleWipLimit || violation.WipLimit == nil || *violation.WipLimit != 1 || violation.CardCount == nil || *violation.CardCount != 1 {
+		t.Fatalf("violação de WIP inesperada: %+v", violation)
+	}
+	// o PUT do card nao serve de atalho para entrar na coluna
+	e.expect(http.StatusConflict, "PUT", fmt.Sprintf("/api/cards/%d", second.ID), editor,
+		fiber.Map{"title": "Segundo", "assigned_to": "editor", "column_id": doing}, nil)
+	assertColumn(t, e.cards(todo, editor), "Segundo")
+	assertColumn(t, e.cards(todo, editor), "Segundo")
+
+	// mover dentro da propria coluna nao conta como entrada
+	first := e.cards(doing, editor)[0]
+	e.moveCard(editor, first.ID, doing, 0)
+
+	// limite suave deixa entrar
+	e.expect(http.StatusOK, "PUT", policyPath, owner, fiber.Map{"wip_limit": 1, "wip_soft": true}, nil)
+	e.moveCard(editor, second.ID, doing, 1)
+	assertColumn(t, e.cards(doing, editor), "Primeiro", "Segundo")
+}
+
+func TestBoardTemplatesAndDuplicate(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	other := e.createUser("outro", false)
+	board, cols := e.createBoard(owner, "Campanha Março")
+
+	var label Label
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/labels", board.ID), owner, fiber.Map{"name": "Fibra", "color": "#ff0000"}, &label)
+	card := e.createCard(cols[0].ID, owner, "Instalação")
+	e.expect(http.StatusNoContent, "POST", fmt.Sprintf("/api/cards/%d/labels/%d", card.ID, label.ID), owner, nil, nil)
+	for _, text := range []string{"Agendar", "Instalar ONU"} {
+		e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/cards/%d/checklist", card.ID), owner, fiber.Map{"text": text}, nil)
+	}
+
+	// so a estrutura
+	var empty Board
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/duplicate", board.ID), owner, fiber.Map{}, &empty)
+	if empty.Title != "Campanha Março (cópia)" || empty.OwnerID != owner {
+		t.Fatalf("copia inesperada: %+v", empty)
+	}
+	emptyCols := e.columns(empty.ID, owner)
+	if len(emptyCols) != 3 || emptyCols[2].Category != CategoryDoneSuccess {
+		t.Fatalf("copia deveria manter as colunas: %+v", emptyCols)
+	}
+	if got := e.cards(emptyCols[0].ID, owner); len(got) != 0 {
+		t.Fatalf("copia sem cards nao deveria ter cards: %+v", got)
+	}
+
+	// com cards, etiquetas e checklist
+	var full Board
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/boards/%d/duplicate", board.ID), owner,
+		fiber.Map{"title": "Campanha Abril", "include_cards": true}, &full)
+	copied := e.cards(e.columns(full.ID, owner)[0].ID, owner)
+	if len(copied) != 1 || len(copied[0].Labels) != 1 || copied[0].Labels[0].Name != "Fibra" || copied[0].ChecklistTotal != 2 {
+		t.Fatalf("card copiado deveria ter etiqueta e checklist: %+v", copied)
+	}
+
+	// quem nao ve o board nao copia
+	e.expect(http.StatusForbidden, "POST", fmt.Sprintf("/api/boards/%d/duplicate", board.ID), other, fiber.Map{}, nil)
+
+	// viewer copia so para um quadro privado e sem responsaveis/prazos
+	viewer := e.createUser("leitor", false)
+	e.addMember(owner, board.ID, viewer, "viewer")
+	e.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/cards/%d", card.ID), owner,
+		fiber.Map{"title": "Instalação", "assigned_to": "dono", "due_date": time.Now().Add(48 * time.Hour)}, nil)
+	dupPath := fmt.Sprintf("/api/boards/%d/duplicate", board.ID)
+	e.expect(http.StatusForbidden, "POST", dupPath, viewer, fiber.Map{"is_public": true}, nil)
+	var viewerCopy Board
+	e.expect(http.StatusCreated, "POST", dupPath, viewer, fiber.Map{"include_cards": true}, &viewerCopy)
+	if viewerCopy.IsPublic || viewerCopy.OwnerID != viewer {
+		t.Fatalf("cópia do viewer deveria ser privada e dele: %+v", viewerCopy)
+	}
+	if got := e.cards(e.columns(viewerCopy.ID, viewer)[0].ID, viewer); len(got) != 1 || got[0].AssignedTo != "" || got[0].DueDate != nil {
+		t.Fatalf("cópia do viewer não deveria levar responsável nem prazo: %+v", got)
+	}
+
+	// template salvo a partir do board, usado por outro usuario
+	var tpl BoardTemplate
+	e.expect(http.StatusCreated, "POST", "/api/board-templates", owner,
+		fiber.Map{"name": "Campanha", "board_id": board.ID, "include_cards": true, "is_shared": true}, &tpl)
+	if len(tpl.Columns) != 3 || len(tpl.Labels) != 1 || len(tpl.Cards) != 1 || len(tpl.Cards[0].Checklist) != 2 {
+		t.Fatalf("template deveria guardar colunas, etiquetas e checklist: %+v", tpl)
+	}
+	var templates []BoardTemplate
+	e.expect(http.StatusOK, "GET", "/api/board-templates", other, nil, &templates)
+	if len(templates) != len(builtinBoardTemplates)+1 {
+		t.Fatalf("template compartilhado deveria aparecer para outros: %+v", templates)
+	}
+	var fromTemplate Board
+	e.expect(http.StatusCreated, "POST", "/api/boards", other, fiber.Map{"title": "Campanha Maio", "template_id": tpl.ID}, &fromTemplate)
+	fromCols := e.columns(fromTemplate.ID, other)
+	if got := e.cards(fromCols[0].ID, other); len(got) != 1 || got[0].Title != "Instalação" || got[0].ChecklistDone != 0 {
+		t.Fatalf("board do template deveria ter o card esqueleto: %+v", got)
+	}
+
+	// template privado e invisivel para os outros
+	var private BoardTemplate
+	e.expect(http.StatusCreated, "POST", "/api/board-templates", owner, fiber.Map{
+		"name":    "Privado",
+		"columns": []fiber.Map{{"title": "Fila", "category": "backlog"}, {"title": "Feito", "category": "done_success"}},
+	}, &private)
+	e.expect(http.StatusBadRequest, "POST", "/api/boards", other, fiber.Map{"title": "X", "template_id": private.ID}, nil)
+	e.expect(http.StatusBadRequest, "POST", "/api/board-templates", owner, fiber.Map{"name": "Vazio"}, nil)
+	e.expect(http.StatusBadRequest, "POST", "/api/board-templates", owner, fiber.Map{
+		"name":    "Etiquetas repetidas",
+		"columns": []fiber.Map{{"title": "Fila"}},
+		"labels":  []fiber.Map{{"name": "Fibra"}, {"name": "fibra"}},
+	}, nil)
+}
+
+func TestRecurringCards(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	tecnico := e.createUser("tecnico", false)
+	board, cols := e.createBoard(owner, "Rotinas")
+	e.addMember(owner, board.ID, tecnico, "editor")
+
+	var model Card
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/columns/%d/cards", cols[1].ID), owner,
+		fiber.Map{"title": "Backup das OLTs", "assigned_to": "tecnico"}, &model)
+	e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/cards/%d/checklist", model.ID), owner, fiber.Map{"text": "Exportar config"}, nil)
+
+	path := fmt.Sprintf("/api/cards/%d/recurrence", model.ID)
+	e.expect(http.StatusNotFound, "GET", path, owner, nil, nil)
+	e.expect(http.StatusBadRequest, "PUT", path, owner, fiber.Map{"frequency": "weekly"}, nil)
+	e.expect(http.StatusBadRequest, "PUT", path, owner, fiber.Map{"frequency": "cron", "cron": "nunca"}, nil)
+
+	var rule CardRecurrence
+	e.expect(http.StatusOK, "PUT", path, owner,
+		fiber.Map{"frequency": "weekly", "weekdays": []int{1}, "time": "07:00", "column_id": cols[0].ID, "due_in_days": 2}, &rule)
+	if rule.ColumnID != cols[0].ID || !rule.NextRunAt.After(time.Now()) || rule.NextRunAt.Weekday() != time.Monday {
+		t.Fatalf("recorrência inesperada: %+v", rule)
+	}
+
+	// nada vence antes da hora
+	if n, err := e.app.spawnDueRecurrences(context.Background(), time.Now()); err != nil || n != 0 {
+		t.Fatalf("nenhuma ocorrência deveria vencer agora: %d %v", n, err)
+	}
+
+	runAt := rule.NextRunAt
+	if n, err := e.app.spawnDueRecurrences(context.Background(), runAt.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("deveria gerar uma ocorrência: %d %v", n, err)
+	}
+	spawned := e.cards(cols[0].ID, owner)
+	if len(spawned) != 1 || spawned[0].Title != "Backup das OLTs" || spawned[0].AssignedTo != "tecnico" || spawned[0].ChecklistTotal != 1 {
+		t.Fatalf("card gerado inesperado: %+v", spawned)
+	}
+	if spawned[0].DueDate == nil || !spawned[0].DueDate.Equal(runAt.AddDate(0, 0, 2)) {
+		t.Fatalf("prazo do card gerado deveria ser 2 dias depois da ocorrência: %+v", spawned[0].DueDate)
+	}
+	assertColumn(t, e.cards(cols[1].ID, owner), "Backup das OLTs")
+
+	// a mesma ocorrencia nao gera de novo
+	if n, _ := e.app.spawnDueRecurrences(context.Background(), runAt.Add(2*time.Minute)); n != 0 {
+		t.Fatalf("ocorrência repetida: %d", n)
+	}
+	e.expect(http.StatusOK, "GET", path, owner, nil, &rule)
+	if rule.LastRunAt == nil || !rule.NextRunAt.After(runAt) {
+		t.Fatalf("próxima ocorrência deveria avançar: %+v", rule)
+	}
+
+	var notifications []Notification
+	e.expect(http.StatusOK, "GET", "/api/notifications", tecnico, nil, &notifications)
+	found := false
+	for _, n := range notifications {
+		if n.RelatedCardID != nil && *n.RelatedCardID == spawned[0].ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("responsável deveria ser notificado da ocorrência: %+v", notifications)
+	}
+
+	e.expect(http.StatusNoContent, "DELETE", path, owner, nil, nil)
+	e.expect(http.StatusNotFound, "GET", path, owner, nil, nil)
+}
+
+func TestDueReminders(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	tecnico := e.createUser("tecnico", false)
+	board, cols := e.createBoard(owner, "Prazos")
+	e.addMember(owner, board.ID, tecnico, "editor")
+
+	now := time.Now()
+	withDue := func(columnID int, title string, due time.Time) Card {
+		var card Card
+		e.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/columns/%d/cards", columnID), owner,
+			fiber.Map{"title": title, "assigned_to": "tecnico", "due_date": due}, &card)
+		return card
+	}
+	soon := withDue(cols[0].ID, "Vence logo", now.Add(2*time.Hour))
+	late := withDue(cols[1].ID, "Atrasada", now.Add(-time.Hour))
+	withDue(cols[0].ID, "Longe", now.Add(72*time.Hour))
+	withDue(cols[2].ID, "Concluida", now.Add(-time.Hour))
+
+	sent, err := e.app.sendDueReminders(context.Background(), now, 24*time.Hour)
+	if err != nil || sent != 2 {
+		t.Fatalf("deveria enviar 2 lembretes: %d %v", sent, err)
+	}
+	reminders := func() map[int]string {
+		var notifications []Notification
+		e.expect(http.StatusOK, "GET", "/api/notifications", tecnico, nil, &notifications)
+		got := make(map[int]string)
+		for _, n := range notifications {
+			if n.Type == ReminderDueSoon || n.Type == ReminderOverdue {
+				got[*n.RelatedCardID] += n.Type + ";"
+			}
+		}
+		return got
+	}
+	if got := reminders(); len(got) != 2 || got[soon.ID] != "due_soon;" || got[late.ID] != "overdue;" {
+		t.Fatalf("lembretes inesperados: %+v", got)
+	}
+
+	// a proxima varredura nao repete
+	if sent, _ := e.app.sendDueReminders(context.Background(), now.Add(time.Minute), 24*time.Hour); sent != 0 {
+		t.Fatalf("lembretes repetidos: %d", sent)
+	}
+
+	// quando o prazo passa, o due_soon vira overdue
+	if sent, _ := e.app.sendDueReminders(context.Background(), now.Add(3*time.Hour), 24*time.Hour); sent != 1 {
+		t.Fatalf("deveria avisar o atraso do card que vencia logo: %d", sent)
+	}
+	if got := reminders(); got[soon.ID] != "due_soon;overdue;" && got[soon.ID] != "overdue;due_soon;" {
+		t.Fatalf("card deveria ter due_soon e overdue: %+v", got)
+	}
+
+	// responsavel desconhecido nao queima o lembrete: sai quando o username e corrigido
+	orphan := withDue(cols[0].ID, "Sem dono valido", now.Add(-30*time.Minute))
+	if _, err := e.app.db.Exec(context.Background(), "UPDATE cards SET assigned_to = 'fantasma' WHERE id = $1", orphan.ID); err != nil {
+		t.Fatal(err)
+	}
+	if sent, err := e.app.sendDueReminders(context.Background(), now.Add(3*time.Hour), 24*time.Hour); err != nil || sent != 0 {
+		t.Fatalf("sem responsável válido nada é enviado: %d %v", sent, err)
+	}
+	if _, err := e.app.db.Exec(context.Background(), "UPDATE cards SET assigned_to = 'tecnico' WHERE id = $1", orphan.ID); err != nil {
+		t.Fatal(err)
+	}
+	if sent, err := e.app.sendDueReminders(context.Background(), now.Add(3*time.Hour), 24*time.Hour); err != nil || sent != 1 {
+		t.Fatalf("lembrete deveria sair depois da correção: %d %v", sent, err)
+	}
+}
+
+func TestAvatarUploadSniffsContent(t *testing.T) {
+	e := newTestEnv(t)
+	user := e.createUser("usuario", false)
+
+	// html com nome e Content-Type de imagem nao passa
+	status, raw := e.upload("/api/user/avatar", user, "avatar", "foto.html", "image/png", []byte("<html><script>alert(1)</script></html>"))
+	if status != http.StatusBadRequest {
+		t.Fatalf("html enviado como imagem deveria dar 400: %d %s", status, raw)
+	}
+
+	// a extensao vem do tipo detectado, nao do nome enviado
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	status, raw = e.upload("/api/user/avatar", user, "avatar", "foto.html", "text/html", buf.Bytes())
+	if status != http.StatusOK {
+		t.Fatalf("png deveria ser aceito: %d %s", status, raw)
+	}
+	var out struct {
+		AvatarURL string `json:"avatar_url"`
+	}
+	json.Unmarshal(raw, &out)
+	if !strings.HasSuffix(out.AvatarURL, ".png") {
+		t.Fatalf("avatar deveria ser gravado como .png: %q", out.AvatarURL)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	e := newTestEnv(t)
+	ana := e.createUser("ana", false)
+	bia := e.createUser("bia", false)
+	_, anaColumns := e.createBoard(ana, "Financeiro")
+	biaBoard, biaColumns := e.createBoard(bia, "Pessoal")
+
+	e.createCard(anaColumns[0].ID, ana, "Faturamento de março")
+	e.createCard(anaColumns[0].ID, ana, "Faturamento de abril <urgente>")
+	old := e.createCard(anaColumns[0].ID, ana, "Faturamento antigo")
+	e.expect(http.StatusOK, "POST", fmt.Sprintf("/api/cards/%d/archive", old.ID), ana, nil, nil)
+	e.createCard(biaColumns[0].ID, bia, "Faturamento da Bia")
+
+	type page struct {
+		Results []SearchResult `json:"results"`
+		Total   int64          `json:"total"`
+	}
+	search := func(userID, query string) page {
+		t.Helper()
+		var p page
+		e.expect(http.StatusOK, "GET", "/api/search?q="+query, userID, nil, &p)
+		return p
+	}
+
+	// arquivados e boards privados de outros ficam de fora; total conta alem da pagina
+	p := search(ana, "faturamento&limit=1")
+	if p.Total != 2 || len(p.Results) != 1 {
+		t.Fatalf("busca da ana: total %d, %d resultados", p.Total, len(p.Results))
+	}
+	p = search(ana, "faturamento")
+	for _, r := range p.Results {
+		if r.Type != "card" || strings.Contains(r.Title, "antigo") || strings.Contains(r.Title, "Bia") {
+			t.Fatalf("resultado inesperado para a ana: %+v", r)
+		}
+		// o trecho vem escapado, so com os marcadores de destaque
+		if strings.Contains(r.Title, "abril") && !strings.Contains(r.Snippet, "<mark>Faturamento</mark>") {
+			t.Fatalf("trecho sem destaque: %q", r.Snippet)
+		}
+		if strings.Contains(r.Title, "abril") && !strings.Contains(r.Snippet, "&lt;urgente&gt;") {
+			t.Fatalf("trecho sem escape do HTML: %q", r.Snippet)
+		}
+	}
+	if p = search(ana, "faturamento&include_archived=true"); p.Total != 3 {
+		t.Fatalf("com include_archived deveriam vir 3 cards: %d", p.Total)
+	}
+	if p = search(bia, "faturamento"); p.Total != 1 || p.Results[0].Title != "Faturamento da Bia" {
+		t.Fatalf("busca da bia: %+v", p)
+	}
+
+	// membro do board passa a encontrar os cards dele
+	e.addMember(bia, biaBoard.ID, ana, "viewer")
+	if p = search(ana, "faturamento"); p.Total != 3 {
+		t.Fatalf("ana como membro deveria ver 3 cards: %d", p.Total)
+	}
+	e.expect(http.StatusBadRequest, "GET", "/api/search?q=", ana, nil, nil)
+}
+
+func TestBoardEventsBacklog(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	board, _ := e.createBoard(owner, "Eventos")
+	var base int64
+	e.app.db.QueryRow(context.Background(), "SELECT COALESCE((SELECT last_seq FROM board_event_seqs WHERE board_id = $1), 0)", board.ID).Scan(&base)
+	for i := 0; i < 5; i++ {
+		e.app.broadcast(board.ID, WsMessage{Type: "CARD_UPDATED", Payload: fiber.Map{"n": i}})
+	}
+	last := base + 5
+
+	decode := func(raw [][]byte) []WsMessage {
+		t.Helper()
+		messages := make([]WsMessage, 0, len(raw))
+		for _, r := range raw {
+			var m WsMessage
+			if err := json.Unmarshal(r, &m); err != nil {
+				t.Fatalf("mensagem inválida: %s", r)
+			}
+			messages = append(messages, m)
+		}
+		return messages
+	}
+	resync := func(label string, since int64) {
+		t.Helper()
+		got := decode(e.app.boardEventsBacklog(board.ID, since, true))
+		if len(got) != 1 || got[0].Type != "RESYNC_REQUIRED" {
+			t.Fatalf("%s: esperado RESYNC_REQUIRED, veio %+v", label, got)
+		}
+	}
+
+	// conexao nova so recebe o seq atual
+	if got := decode(e.app.boardEventsBacklog(board.ID, 0, false)); len(got) != 1 || got[0].Type != "CONNECTED" {
+		t.Fatalf("conexão nova: %+v", got)
+	}
+	if got := e.app.boardEventsBacklog(board.ID, last, true); got != nil {
+		t.Fatalf("cliente em dia não deveria receber nada: %d mensagens", len(got))
+	}
+
+	// reconexao recebe exatamente o que perdeu, em ordem
+	got := decode(e.app.boardEventsBacklog(board.ID, last-3, true))
+	if len(got) != 3 || got[0].Seq != last-2 || got[2].Seq != last || got[0].Type != "CARD_UPDATED" {
+		t.Fatalf("eventos perdidos: %+v", got)
+	}
+	resync("since no futuro", last+1)
+
+	// buraco no meio (evento perdido) ou no inicio (retencao) pede resync
+	if _, err := e.app.db.Exec(context.Background(), "DELETE FROM board_events WHERE board_id = $1 AND seq = $2", board.ID, last-1); err != nil {
+		t.Fatal(err)
+	}
+	resync("buraco no meio", last-3)
+	if _, err := e.app.db.Exec(context.Background(), "DELETE FROM board_events WHERE board_id = $1 AND seq <= $2", board.ID, last-3); err != nil {
+		t.Fatal(err)
+	}
+	resync("eventos já removidos", last-4)
+
+	// atraso maior que maxReplayEvents tambem
+	e.app.broadcast(board.ID, WsMessage{Type: "CARD_UPDATED"})
+	if _, err := e.app.db.Exec(context.Background(), "UPDATE board_event_seqs SET last_seq = last_seq + $2 WHERE board_id = $1", board.ID, maxReplayEvents); err != nil {
+		t.Fatal(err)
+	}
+	resync("atraso acima do limite", last)
+}
+
+func TestCardAttachments(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.createUser("dono", false)
+	editor := e.createUser("editora", false)
+	board, columns := e.createBoard(owner, "Anexos")
+	e.addMember(owner, board.ID, editor, "editor")
+	card := e.createCard(columns[0].ID, owner, "Com anexo")
+	base := fmt.Sprintf("/api/cards/%d/attachments", card.ID)
+
+	// o tipo vem do conteudo: svg e html nao passam, mesmo declarados como imagem ou texto
+	for _, bad := range []struct{ name, contentType, data string }{
+		{"logo.svg", "image/svg+xml", `<?xml version="1.0"?><svg onload="alert(1)"/>`},
+		{"nota.txt", "text/plain", "<!DOCTYPE html><script>alert(1)</script>"},
+	} {
+		if status, raw := e.upload(base, editor, "file", bad.name, bad.contentType, []byte(bad.data)); status != http.StatusUnsupportedMediaType {
+			t.Fatalf("%s deveria dar 415: %d %s", bad.name, status, raw)
+		}
+	}
+
+	status, raw := e.upload(base, editor, "file", "../notas.txt", "image/png", []byte("linha 1\nlinha 2\n"))
+	if status != http.StatusCreated {
+		t.Fatalf("upload: %d %s", status, raw)
+	}
+	var attachment Attachment
+	json.Unmarshal(raw, &attachment)
+	if attachment.ContentType != "text/plain" || attachment.FileName != "notas.txt" || attachment.SizeBytes != 16 {
+		t.Fatalf("anexo gravado: %+v", attachment)
+	}
+
+	var list []Attachment
+	e.expect(http.StatusOK, "GET", base, owner, nil, &list)
+	if len(list) != 1 || list[0].ID != attachment.ID {
+		t.Fatalf("listagem: %+v", list)
+	}
+
+	// download devolve o tipo detectado e o conteudo gravado no disco local
+	req := httptest.NewRequest("GET", fmt.Sprintf("%s/%d/download", base, attachment.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+e.token(owner))
+	resp, err := e.fiber.Test(req, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "linha 1\nlinha 2\n" ||
+		resp.Header.Get("Content-Type") != "text/plain" || resp.Header.Get("X-Content-Type-Options") != "nosniff" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Fatalf("download: %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+
+	// so quem enviou ou o dono remove
+	outsider := e.createUser("outra", false)
+	e.addMember(owner, board.ID, outsider, "editor")
+	e.expect(http.StatusForbidden, "DELETE", fmt.Sprintf("%s/%d", base, attachment.ID), outsider, nil, nil)
+	e.expect(http.StatusNoContent, "DELETE", fmt.Sprintf("%s/%d", base, attachment.ID), owner, nil, nil)
+	e.expect(http.StatusNotFound, "GET", fmt.Sprintf("%s/%d/download", base, attachment.ID), owner, nil, nil)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims SupabaseClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("assinar token: %v", err)
+	}
+	return signed
+}
+
+func testClaims(sub, issuer string, exp time.Time) SupabaseClaims {
+	return SupabaseClaims{
+		UserID: sub,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{"authenticated"},
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+}
+
+func TestSupabaseIdentityJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	// o JWKS comeca so com a chave RSA; a EC aparece depois (rotacao)
+	var keys atomic.Value
+	keys.Store([]map[string]string{rsaJWK("rsa-1", &rsaKey.PublicKey)})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys.Load()})
+	}))
+	defer srv.Close()
+
+	const issuer = "https://projeto.supabase.co/auth/v1"
+	jwks := newJWKS(srv.URL, "")
+	jwks.minRefresh = 0
+	id := &SupabaseIdentity{JWTSecret: "segredo-legado", JWKS: jwks, Issuer: issuer, Leeway: 30 * time.Second}
+	exp := time.Now().Add(time.Hour)
+
+	if sub, err := id.ValidateToken(signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, testClaims("u-rsa", issuer, exp))); err != nil || sub != "u-rsa" {
+		t.Fatalf("RS256: sub %q, erro %v", sub, err)
+	}
+	// chave em cache: nao busca de novo
+	id.ValidateToken(signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, testClaims("u-rsa", issuer, exp)))
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("JWKS buscado %d vezes, esperado 1", n)
+	}
+
+	// kid novo forca a busca do JWKS atualizado
+	keys.Store([]map[string]string{rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey)})
+	if sub, err := id.ValidateToken(signTestToken(t, jwt.SigningMethodES256, "ec-1", ecKey, testClaims("u-ec", issuer, exp))); err != nil || sub != "u-ec" {
+		t.Fatalf("ES256 com kid novo: sub %q, erro %v", sub, err)
+	}
+
+	// HS256 legado continua valendo
+	if sub, err := id.ValidateToken(signTestToken(t, jwt.SigningMethodHS256, "", []byte("segredo-legado"), testClaims("u-hs", issuer, exp))); err != nil || sub != "u-hs" {
+		t.Fatalf("HS256: sub %q, erro %v", sub, err)
+	}
+
+	// expirado ha pouco, dentro da tolerancia de relogio
+	if _, err := id.ValidateToken(signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, testClaims("u", issuer, time.Now().Add(-10*time.Second)))); err != nil {
+		t.Fatalf("token dentro da tolerância foi recusado: %v", err)
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rejected := map[string]string{
+		"emissor errado":     signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, testClaims("u", "https://outro/auth/v1", exp)),
+		"expirado":           signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, testClaims("u", issuer, time.Now().Add(-time.Minute))),
+		"kid desconhecido":   signTestToken(t, jwt.SigningMethodRS256, "rsa-9", rsaKey, testClaims("u", issuer, exp)),
+		"assinatura errada":  signTestToken(t, jwt.SigningMethodRS256, "rsa-1", otherKey, testClaims("u", issuer, exp)),
+		"segredo HS errado":  signTestToken(t, jwt.SigningMethodHS256, "", []byte("outro"), testClaims("u", issuer, exp)),
+		"algoritmo recusado": signTestToken(t, jwt.SigningMethodRS512, "rsa-1", rsaKey, testClaims("u", issuer, exp)),
+	}
+	for name, token := range rejected {
+		if _, err := id.ValidateToken(token); err == nil {
+			t.Errorf("%s: token deveria ser recusado", name)
+		}
+	}
+}
+
+func TestJWKSFromFile(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	raw, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{ecJWK("ec-file", &ecKey.PublicKey)}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	id := &SupabaseIdentity{JWKS: newJWKS("", path)}
+	token := signTestToken(t, jwt.SigningMethodES256, "ec-file", ecKey, testClaims("u-file", "", time.Now().Add(time.Hour)))
+	if sub, err := id.ValidateToken(token); err != nil || sub != "u-file" {
+		t.Fatalf("ES256 via arquivo: sub %q, erro %v", sub, err)
+	}
+
+	// sem segredo, tokens HS256 sao recusados (nao e erro de configuracao)
+	hs := signTestToken(t, jwt.SigningMethodHS256, "", []byte("qualquer"), testClaims("u", "", time.Now().Add(time.Hour)))
+	if _, err := id.ValidateToken(hs); err == nil || err == ErrAuthConfig {
+		t.Fatalf("HS256 sem segredo: erro %v, esperado token inválido", err)
+	}
+	if _, err := (&SupabaseIdentity{}).ValidateToken(token); err != ErrAuthConfig {
+		t.Fatalf("sem segredo nem JWKS: erro %v, esperado ErrAuthConfig", err)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// estrutura label
+type Label struct {
+	ID        int       `json:"id"`
+	BoardID   int       `json:"board_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// board da rota /boards/:id, ja checando o papel do usuario
+func (app *App) boardForRequest(c *fiber.Ctx, capability BoardCapability) (boardID int, ok bool, resp error) {
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
+	}
+	if ok, resp := app.requireBoardCapability(c, c.Locals("userID").(string), boardID, capability); !ok {
+		return 0, false, resp
+	}
+	return boardID, true, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// parsear ?label_ids=1,2,3
+func parseLabelFilter(raw string) ([]int, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	ids := make([]int, 0)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// endpoint listar labels do board
+func (app *App) getBoardLabels(c *fiber.Ctx) error {
+	boardID, ok, resp := app.boardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	rows, err := app.db.Query(context.Background(),
+		"SELECT id, board_id, name, color, created_at FROM board_labels WHERE board_id = $1 ORDER BY name", boardID)
+	if err != nil {
+		log.Printf("Erro ao buscar labels do board %d: %v", boardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar etiquetas"})
+	}
+	defer rows.Close()
+	labels := make([]Label, 0)
+	for rows.Next() {
+		var l Label
+		if err := rows.Scan(&l.ID, &l.BoardID, &l.Name, &l.Color, &l.CreatedAt); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler etiqueta"})
+		}
+		labels = append(labels, l)
+	}
+	return c.JSON(labels)
+}
+
+// endpoint criar label
+func (app *App) createBoardLabel(c *fiber.Ctx) error {
+	boardID, ok, resp := app.boardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+	var label Label
+	if err := c.BodyParser(&label); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Dados de etiqueta inválidos"})
+	}
+	label.Name = strings.TrimSpace(label.Name)
+	if label.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O nome da etiqueta é obrigatório"})
+	}
+	if label.Color == "" {
+		label.Color = "#8b949e"
+	}
+	label.BoardID = boardID
+	err := app.db.QueryRow(context.Background(),
+		"INSERT INTO board_labels (board_id, name, color) VALUES ($1, $2, $3) RETURNING id, created_at",
+		boardID, label.Name, label.Color).Scan(&label.ID, &label.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Já existe uma etiqueta com esse nome neste quadro."})
+		}
+		log.Printf("Erro ao criar label no board %d: %v", boardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar etiqueta"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_CREATED", Payload: label, SenderID: userID})
+	return c.Status(201).JSON(label)
+}
+
+// endpoint atualizar label
+func (app *App) updateBoardLabel(c *fiber.Ctx) error {
+	boardID, ok, resp := app.boardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da etiqueta inválido"})
+	}
+	userID := c.Locals("userID").(string)
+	var label Label
+	if err := c.BodyParser(&label); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Dados de etiqueta inválidos"})
+	}
+	label.Name = strings.TrimSpace(label.Name)
+	if label.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O nome da etiqueta é obrigatório"})
+	}
+	err = app.db.QueryRow(context.Background(), `
+		UPDATE board_labels SET name = $1, color = COALESCE(NULLIF($2, ''), color)
+		WHERE id = $3 AND board_id = $4
+		RETURNING id, board_id, name, color, created_at`,
+		label.Name, label.Color, labelID, boardID).Scan(&label.ID, &label.BoardID, &label.Name, &label.Color, &label.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Etiqueta não encontrada"})
+		}
+		if isUniqueViolation(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Já existe uma etiqueta com esse nome neste quadro."})
+		}
+		log.Printf("Erro ao atualizar label %d: %v", labelID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar etiqueta"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_UPDATED", Payload: label, SenderID: userID})
+	return c.JSON(label)
+}
+
+// endpoint deletar label
+func (app *App) deleteBoardLabel(c *fiber.Ctx) error {
+	boardID, ok, resp := app.boardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da etiqueta inválido"})
+	}
+	userID := c.Locals("userID").(string)
+	cmdTag, err := app.db.Exec(context.Background(), "DELETE FROM board_labels WHERE id = $1 AND board_id = $2", labelID, boardID)
+	if err != nil {
+		log.Printf("Erro ao deletar label %d: %v", labelID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar etiqueta"})
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Etiqueta não encontrada"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_DELETED", Payload: fiber.Map{"label_id": labelID}, SenderID: userID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// endpoint aplicar label no card
+func (app *App) addCardLabel(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da etiqueta inválido"})
+	}
+	userID := c.Locals("userID").(string)
+
+	var label Label
+	err = app.db.QueryRow(context.Background(),
+		"SELECT id, board_id, name, color, created_at FROM board_labels WHERE id = $1 AND board_id = $2",
+		labelID, boardID).Scan(&label.ID, &label.BoardID, &label.Name, &label.Color, &label.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Etiqueta não encontrada neste quadro"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar etiqueta"})
+	}
+	_, err = app.db.Exec(context.Background(),
+		"INSERT INTO card_labels (card_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", cardID, labelID)
+	if err != nil {
+		log.Printf("Erro ao aplicar label %d no card %d: %v", labelID, cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao aplicar etiqueta"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_ATTACHED", Payload: fiber.Map{"card_id": cardID, "label": label}, SenderID: userID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// endpoint remover label do card
+func (app *App) removeCardLabel(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da etiqueta inválido"})
+	}
+	userID := c.Locals("userID").(string)
+	_, err = app.db.Exec(context.Background(), "DELETE FROM card_labels WHERE card_id = $1 AND label_id = $2", cardID, labelID)
+	if err != nil {
+		log.Printf("Erro ao remover label %d do card %d: %v", labelID, cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover etiqueta"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_DETACHED", Payload: fiber.Map{"card_id": cardID, "label_id": labelID}, SenderID: userID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,18 +1,14 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,7 +21,6 @@ import (
 	"github.com/gofiber/websocket/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
@@ -39,6 +34,9 @@ type User struct {
 	Avatar    string    `json:"avatar" db:"avatar"`
 	Role      string    `json:"role" db:"role"`
 	IsAdmin   bool      `json:"is_admin" db:"is_admin"`
+	// preenchidos a partir do perfil
+	DisplayName string `json:"display_name,omitempty" db:"-"`
+	Active      bool   `json:"active" db:"-"`
 }
 
 // estrutura board
@@ -56,11 +54,13 @@ type Board struct {
 
 // estrutura column
 type Column struct {
-	ID       int    `json:"id" db:"id"`
-	BoardID  int    `json:"board_id" db:"board_id"`
-	Title    string `json:"title" db:"title"`
-	Position int    `json:"position" db:"position"`
-	Color    string `json:"color" db:"color"`
+	ID       int            `json:"id" db:"id"`
+	BoardID  int            `json:"board_id" db:"board_id"`
+	Title    string         `json:"title" db:"title"`
+	Position int            `json:"position" db:"position"`
+	Color    string         `json:"color" db:"color"`
+	Category ColumnCategory `json:"category" db:"category"`
+	ColumnPolicy
 }
 
 // estrutura card
@@ -76,6 +76,12 @@ type Card struct {
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 	Position    int        `json:"position" db:"position"`
 	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	Labels      []Label    `json:"labels,omitempty" db:"-"`
+
+	ChecklistTotal      int  `json:"checklist_total,omitempty" db:"-"`
+	ChecklistDone       int  `json:"checklist_done,omitempty" db:"-"`
+	ChecklistCompletion *int `json:"checklist_completion,omitempty" db:"-"`
 }
 
 // estrutura notification
@@ -105,6 +111,8 @@ type BoardInvitation struct {
 
 // estrutura wsmessage
 type WsMessage struct {
+	ID       string      `json:"id,omitempty"`
+	Seq      int64       `json:"seq,omitempty"`
 	SenderID string      `json:"sender_id,omitempty"`
 	Type     string      `json:"type"`
 	Payload  interface{} `json:"payload"`
@@ -172,57 +180,28 @@ type ReorderPayload struct {
 
 // estrutura App
 type App struct {
-	db       *pgxpool.Pool
-	clients  map[int]map[*websocket.Conn]bool
-	colLocks struct {
+	db            *pgxpool.Pool
+	hub           *Hub
+	storage       Storage
+	boards        BoardStore
+	columns       ColumnStore
+	cards         CardStore
+	contatos      ContatoStore
+	notifications NotificationStore
+	events        EventStore
+	users         UserStore
+	profiles      ProfileStore
+	roles         RoleStore
+	identity      IdentityProvider
+	names         displayNameCache
+	colLocks      struct {
 		mu    sync.Mutex
 		locks map[int]*sync.Mutex
 	}
-}
-
-// mapeamento global
-var userDisplayNameMap = map[string]string{
-	"eduardo@kanban.local": "Eduardo Tomaz",
-	"alison@kanban.local":  "Alison Silva",
-	"marques@kanban.local": "Gabriel Marques",
-	"rosa@kanban.local":    "Gabriel Rosa",
-	"miyake@kanban.local":  "João Miyake",
-	"gomes@kanban.local":   "João Gomes",
-	"rodrigo@kanban.local": "Rodrigo Akira",
-	"rubens@kanban.local":  "Rubens Leite",
-	"kaiky@kanban.local":   "Kaiky Leandro",
-	"pedro@kanban.local":   "Pedro Santos",
-	"diego@kanban.local":   "Diego Sousa",
-	"cesar@kanban.local":   "César Bragança",
-}
-
-// pegar mapeamento
-func (app *App) getDisplayName(ctx context.Context, tx pgx.Tx, userID string) string {
-	var email string
-	var username sql.NullString
-
-	var querier interface {
-		QueryRow(context.Context, string, ...interface{}) pgx.Row
-	}
-	if tx != nil {
-		querier = tx
-	} else {
-		querier = app.db
-	}
-
-	err := querier.QueryRow(ctx, "SELECT email, raw_user_meta_data->>'username' FROM auth.users WHERE id = $1", userID).Scan(&email, &username)
-	if err != nil {
-		log.Printf("Aviso: não foi possível encontrar o nome para o userID %s: %v", userID, err)
-		return "Um usuário"
-	}
-
-	if name, ok := userDisplayNameMap[email]; ok {
-		return name
-	}
-	if username.Valid && username.String != "" {
-		return username.String
+	eventLocks struct {
+		mu    sync.Mutex
+		locks map[int]*sync.Mutex
 	}
-	return email
 }
 
 // claims Supabase JWT
@@ -249,6 +228,18 @@ func (app *App) connectDB() error {
 	return nil
 }
 
+// validar token JWT no provedor de identidade, retorna o ID do usuario
+func (app *App) validateToken(tokenString string) (string, int, error) {
+	userID, err := app.identity.ValidateToken(tokenString)
+	if errors.Is(err, ErrAuthConfig) {
+		return "", fiber.StatusInternalServerError, err
+	}
+	if err != nil {
+		return "", fiber.StatusUnauthorized, err
+	}
+	return userID, 0, nil
+}
+
 // middleware auth
 func (app *App) authMiddleware(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
@@ -259,31 +250,53 @@ func (app *App) authMiddleware(c *fiber.Ctx) error {
 	if len(parts) != 2 || parts[0] != "Bearer" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Formato de autorização inválido. Esperado: Bearer <token>"})
 	}
-	tokenString := parts[1]
-	jwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
-	if jwtSecret == "" {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Configuração do servidor incorreta"})
+	userID, status, err := app.validateToken(parts[1])
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
-	token, err := jwt.ParseWithClaims(tokenString, &SupabaseClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("método de assinatura inesperado: %v", token.Header["alg"])
+	c.Locals("userID", userID)
+	return c.Next()
+}
+
+// subprotocolo usado pelo navegador para enviar o token no handshake
+const wsTokenSubprotocol = "bearer"
+
+// token do websocket via ?token= ou Sec-WebSocket-Protocol: bearer, <token>
+func wsTokenFromRequest(c *fiber.Ctx) string {
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	protocols := strings.Split(c.Get("Sec-WebSocket-Protocol"), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == wsTokenSubprotocol {
+			return strings.TrimSpace(protocols[i+1])
 		}
-		return []byte(jwtSecret), nil
-	}, jwt.WithAudience("authenticated"))
+	}
+	return ""
+}
+
+// middleware do upgrade websocket: autentica e checa acesso ao board
+func (app *App) wsAuthMiddleware(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	boardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token expirado"})
-		}
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token inválido"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
+	}
+	tokenString := wsTokenFromRequest(c)
+	if tokenString == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token de autenticação ausente"})
 	}
-	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token inválido ou expirado"})
+	userID, status, err := app.validateToken(tokenString)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
-	claims, ok := token.Claims.(*SupabaseClaims)
-	if !ok || claims.UserID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Claims do token inválidas ou ID de usuário ausente"})
+	hasPermission, err := app.checkBoardPermission(userID, boardID)
+	if err != nil || !hasPermission {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
 	}
-	c.Locals("userID", claims.UserID)
+	c.Locals("userID", userID)
 	return c.Next()
 }
 
@@ -294,35 +307,26 @@ func (app *App) handleWebSocket(c *websocket.Conn) {
 		c.Close()
 		return
 	}
-	if app.clients[boardID] == nil {
-		app.clients[boardID] = make(map[*websocket.Conn]bool)
-	}
-	app.clients[boardID][c] = true
-	defer func() {
-		delete(app.clients[boardID], c)
-		if len(app.clients[boardID]) == 0 {
-			delete(app.clients, boardID)
-		}
-		c.Close()
-	}()
-	for {
-		if _, _, err := c.ReadMessage(); err != nil {
-			break
-		}
-	}
+	userID, _ := c.Locals("userID").(string)
+	since, resume := parseSince(c.Query("since"))
+	app.hub.serve(c, boardID, userID, func() [][]byte {
+		return app.boardEventsBacklog(boardID, since, resume)
+	})
 }
 
-// broadcast
+// broadcast, gravando o evento para replay
 func (app *App) broadcast(boardID int, message WsMessage) {
-	if clients, ok := app.clients[boardID]; ok {
-		payloadBytes, _ := json.Marshal(message)
-		for client := range clients {
-			if err := client.WriteMessage(websocket.TextMessage, payloadBytes); err != nil {
-				client.Close()
-				delete(clients, client)
-			}
-		}
+	lock := app.boardEventLock(boardID)
+	lock.Lock()
+	defer lock.Unlock()
+	message.ID = newMessageID()
+	seq, err := app.events.RecordBoardEvent(context.Background(), boardID, message)
+	if err != nil {
+		log.Printf("Erro ao gravar evento %s do board %d: %v", message.Type, boardID, err)
+	} else {
+		message.Seq = seq
 	}
+	app.hub.broadcastMessage(boardID, message)
 }
 
 // avatar users
@@ -332,51 +336,25 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nenhum arquivo de avatar enviado"})
 	}
-	contentType := file.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Formato de arquivo inválido. Apenas imagens são permitidas."})
-	}
-	src, err := file.Open()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao abrir o arquivo"})
-	}
-	defer src.Close()
-	fileBytes, err := io.ReadAll(src)
+	fileBytes, err := readFormFile(file)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao ler o arquivo"})
 	}
-	ext := filepath.Ext(file.Filename)
+	contentType, ext, ok := sniffImage(fileBytes)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Formato de arquivo inválido. Envie uma imagem JPEG, PNG, GIF ou WebP."})
+	}
 	fileName := fmt.Sprintf("avatar-%s%s", userID, ext)
-	supabaseURL := os.Getenv("SUPABASE_PROJECT_URL")
-	supabaseKey := os.Getenv("SUPABASE_SERVICE_KEY")
-	uploadURL := fmt.Sprintf("%s/storage/v1/object/avatars/%s", supabaseURL, fileName)
-	req, err := http.NewRequest("POST", uploadURL, bytes.NewReader(fileBytes))
-	if err != nil {
-		log.Printf("❌ Erro ao criar requisição para o Supabase: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao preparar upload"})
-	}
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("x-upsert", "true")
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("❌ Erro ao fazer upload para o Supabase: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao fazer upload"})
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ Supabase retornou status não-OK: %s, Body: %s", resp.Status, string(body))
+	publicURL, err := app.storage.Put(context.Background(), "avatars", fileName, contentType, fileBytes)
+	if err != nil {
+		log.Printf("❌ Erro ao armazenar avatar: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Falha ao armazenar o arquivo"})
 	}
-	publicURL := fmt.Sprintf("%s/storage/v1/object/public/avatars/%s", supabaseURL, fileName)
-	query := `
-		UPDATE auth.users
-		SET raw_user_meta_data = raw_user_meta_data || jsonb_build_object('avatar_url', $1::text)
-		WHERE id = $2
-	`
-	_, err = app.db.Exec(context.Background(), query, publicURL, userID)
+	profile, err := app.profileFor(context.Background(), userID)
+	if err == nil {
+		profile.AvatarURL = publicURL
+		_, err = app.profiles.SaveProfile(context.Background(), profile)
+	}
 	if err != nil {
 		log.Printf("❌ Erro ao atualizar o avatar do usuário no DB: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar perfil"})
@@ -386,15 +364,7 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 
 // board por id de card
 func (app *App) getBoardIDFromCard(cardID int) (int, error) {
-	var boardID int
-	query := `SELECT c.board_id FROM columns c
-			  INNER JOIN cards ca ON c.id = ca.column_id
-			  WHERE ca.id = $1`
-	err := app.db.QueryRow(context.Background(), query, cardID).Scan(&boardID)
-	if err != nil {
-		return 0, err
-	}
-	return boardID, nil
+	return app.boards.BoardIDForCard(context.Background(), cardID)
 }
 
 // endpoint criar coluna
@@ -406,20 +376,16 @@ func (app *App) createColumn(c *fiber.Ctx) error {
 	if col.BoardID == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "board_id é obrigatório"})
 	}
-	var maxPos sql.NullInt64
-	err := app.db.QueryRow(context.Background(),
-		"SELECT MAX(position) FROM columns WHERE board_id = $1", col.BoardID).Scan(&maxPos)
-	if err != nil {
-		maxPos.Int64 = -1
+	category, ok := parseColumnCategory(string(col.Category))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Categoria inválida. Use backlog, in_progress, done_success ou done_failure."})
+	}
+	col.Category = category
+	if ok, resp := app.requireBoardCapability(c, c.Locals("userID").(string), col.BoardID, CapEdit); !ok {
+		return resp
 	}
-	col.Position = int(maxPos.Int64) + 1
-	query := `
-        INSERT INTO columns (board_id, title, position, color)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id
-    `
-	err = app.db.QueryRow(context.Background(), query,
-		col.BoardID, col.Title, col.Position, col.Color).Scan(&col.ID)
+	// regras da coluna so pelo endpoint de politica
+	col, err := app.columns.CreateColumn(context.Background(), col)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "erro ao criar coluna"})
 	}
@@ -433,24 +399,28 @@ func (app *App) updateColumn(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
 	}
+	boardID, err := app.getBoardIDFromColumn(columnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
+	}
+	if ok, resp := app.requireBoardCapability(c, c.Locals("userID").(string), boardID, CapEdit); !ok {
+		return resp
+	}
 
 	var col Column
 	if err := c.BodyParser(&col); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Dados de coluna inválidos"})
 	}
+	// sem category no payload a coluna mantem a atual
+	if col.Category != "" {
+		if _, ok := parseColumnCategory(string(col.Category)); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Categoria inválida. Use backlog, in_progress, done_success ou done_failure."})
+		}
+	}
 
-	query := `
-		UPDATE columns 
-		SET title = $1, color = $2 
-		WHERE id = $3 
-		RETURNING id, board_id, title, position, color
-	`
-	err = app.db.QueryRow(context.Background(), query, col.Title, col.Color, columnID).Scan(
-		&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color,
-	)
-
+	col, err = app.columns.UpdateColumn(context.Background(), columnID, col.Title, col.Color, col.Category)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar coluna"})
@@ -467,36 +437,25 @@ func (app *App) deleteColumn(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
 	}
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro interno do servidor"})
-	}
-	defer tx.Rollback(context.Background())
-	var cardCount int
-	err = tx.QueryRow(context.Background(), "SELECT COUNT(*) FROM cards WHERE column_id = $1", columnID).Scan(&cardCount)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar cards na coluna"})
-	}
-	if cardCount > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A coluna não pode ser excluída pois contém tarefas."})
-	}
-	var boardID, position int
-	err = tx.QueryRow(context.Background(), "SELECT board_id, position FROM columns WHERE id = $1", columnID).Scan(&boardID, &position)
+	columnBoardID, err := app.getBoardIDFromColumn(columnID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
 	}
-	_, err = tx.Exec(context.Background(), "DELETE FROM columns WHERE id = $1", columnID)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar a coluna"})
+	if ok, resp := app.requireBoardCapability(c, c.Locals("userID").(string), columnBoardID, CapEdit); !ok {
+		return resp
 	}
-	_, err = tx.Exec(context.Background(), "UPDATE columns SET position = position - 1 WHERE board_id = $1 AND position > $2", boardID, position)
+	err = app.columns.DeleteColumn(context.Background(), columnID, c.Locals("userID").(string))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar colunas"})
-	}
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a exclusão"})
+		if errors.Is(err, ErrColumnNotEmpty) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A coluna não pode ser excluída pois contém tarefas."})
+		}
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
+		}
+		log.Printf("Erro ao deletar a coluna %d: %v", columnID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar a coluna"})
 	}
-	app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil})
+	app.broadcast(columnBoardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil})
 	return c.Status(200).JSON(fiber.Map{"status": "deleted"})
 }
 
@@ -507,49 +466,29 @@ func (app *App) deleteBoard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
 	}
 	userID := c.Locals("userID").(string)
-	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar o quadro"})
 	}
-	if ownerID != userID {
+	if board.OwnerID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado. Você não é o dono deste quadro."})
 	}
-	_, err = app.db.Exec(context.Background(), "DELETE FROM boards WHERE id = $1", boardID)
-	if err != nil {
+	if err := app.boards.DeleteBoard(context.Background(), boardID, userID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao deletar o quadro"})
 	}
+	app.hub.closeBoard(boardID)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
 func (app *App) handleGetContatosStatus(c *fiber.Ctx) error {
-	query := `SELECT contato_id, status, anotacao, updated_at, updated_by, assigned_to FROM contato_status`
-	rows, err := app.db.Query(context.Background(), query)
+	statuses, err := app.contatos.ListContatoStatus(context.Background())
 	if err != nil {
 		log.Printf("Erro ao buscar status de contatos: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar dados de status"})
 	}
-	defer rows.Close()
-
-	statuses := make([]ContatoStatus, 0)
-	for rows.Next() {
-		var cs ContatoStatus
-		var anotacao sql.NullString
-		var assignedTo sql.NullString
-
-		if err := rows.Scan(&cs.ContatoID, &cs.Status, &anotacao, &cs.UpdatedAt, &cs.UpdatedBy, &assignedTo); err == nil {
-			cs.Anotacao = anotacao.String
-			if assignedTo.Valid {
-				cs.AssignedTo = &assignedTo.String
-			}
-			statuses = append(statuses, cs)
-		} else {
-			log.Printf("Erro ao escanear status de contato: %v", err)
-		}
-	}
 	return c.JSON(statuses)
 }
 
@@ -585,42 +524,12 @@ func (app *App) handleSetContatoStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		log.Printf("Erro ao iniciar transação para setar status: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro interno do servidor"})
-	}
-	defer tx.Rollback(context.Background())
-
-	var returnedId int
-
-	query := `
-        INSERT INTO contato_status (contato_id, status, anotacao, updated_at, updated_by, assigned_to)
-        VALUES (
-            $1, $2, $3, NOW(), $4,
-            CASE WHEN $2 = 'pendente' THEN NULL ELSE (SELECT assigned_to FROM contato_status WHERE contato_id = $1) END
-        )
-        ON CONFLICT (contato_id)
-        DO UPDATE SET
-            status = EXCLUDED.status,
-            anotacao = EXCLUDED.anotacao,
-            updated_at = NOW(),
-            updated_by = EXCLUDED.updated_by,
-            assigned_to = CASE WHEN EXCLUDED.status = 'pendente' THEN NULL ELSE contato_status.assigned_to END
-        RETURNING id
-    `
-	err = tx.QueryRow(context.Background(), query, payload.ContatoID, payload.Status, payload.Anotacao, userID).Scan(&returnedId)
-
+	returnedId, err := app.contatos.SetContatoStatus(context.Background(), payload.ContatoID, payload.Status, payload.Anotacao, userID)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert do status do contato (%s): %v", payload.Status, err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar o status no banco de dados"})
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
-		log.Printf("Erro ao commitar transação de setar status: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a alteração no banco de dados"})
-	}
-
 	return c.Status(200).JSON(fiber.Map{"status": "success", "id": returnedId})
 }
 
@@ -639,17 +548,7 @@ func (app *App) handleAssignContato(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "contato_id é obrigatório"})
 	}
 
-	query := `
-        INSERT INTO contato_status (contato_id, status, updated_by, assigned_to)
-        VALUES ($1, 'pendente', $2, $3)
-        ON CONFLICT (contato_id)
-        DO UPDATE SET
-            assigned_to = EXCLUDED.assigned_to,
-            updated_at = NOW(),
-            updated_by = EXCLUDED.updated_by
-    `
-
-	_, err := app.db.Exec(context.Background(), query, payload.ContatoID, userID, userID)
+	err := app.contatos.AssignContato(context.Background(), payload.ContatoID, userID, userID)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert para assumir contato: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar a atribuição no banco de dados"})
@@ -677,46 +576,21 @@ func (app *App) handleUnassignContato(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "contato_id é obrigatório"})
 	}
 
-	var isAdmin bool
-	adminCheckQuery := "SELECT COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) FROM auth.users WHERE id = $1"
-	err := app.db.QueryRow(context.Background(), adminCheckQuery, userID).Scan(&isAdmin)
+	canDistribute, err := app.hasPermission(context.Background(), userID, PermContatosDistribute)
 	if err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("Erro ao checar status de admin para o usuário %s: %v", userID, err)
-		}
-		isAdmin = false
+		log.Printf("Erro ao checar permissões do usuário %s: %v", userID, err)
+		canDistribute = false
 	}
 
-	var (
-		cmdTag  pgconn.CommandTag
-		execErr error
-	)
-
-	baseUpdate := `
-        UPDATE contato_status
-        SET 
-            assigned_to = NULL,
-            updated_at = NOW(),
-            updated_by = $2::uuid
-	`
-
-	if isAdmin {
-		query := baseUpdate + " WHERE contato_id = $1"
-		cmdTag, execErr = app.db.Exec(context.Background(), query, payload.ContatoID, userID)
-	} else {
-		query := baseUpdate + " WHERE contato_id = $1 AND assigned_to = $2"
-		cmdTag, execErr = app.db.Exec(context.Background(), query, payload.ContatoID, userID)
-	}
-
-	if execErr != nil {
-		log.Printf("Erro ao desassociar contato (admin=%t): %v", isAdmin, execErr)
+	unassigned, err := app.contatos.UnassignContato(context.Background(), payload.ContatoID, userID, canDistribute)
+	if err != nil {
+		log.Printf("Erro ao desassociar contato (distribuir=%t): %v", canDistribute, err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover associação no banco de dados"})
 	}
 
-	if cmdTag.RowsAffected() == 0 {
-		var exists int
-		app.db.QueryRow(context.Background(), "SELECT 1 FROM contato_status WHERE contato_id = $1", payload.ContatoID).Scan(&exists)
-		if exists != 1 {
+	if !unassigned {
+		exists, _ := app.contatos.ContatoExists(context.Background(), payload.ContatoID)
+		if !exists {
 			return c.Status(404).JSON(fiber.Map{"error": "Tarefa não encontrada."})
 		}
 		return c.Status(403).JSON(fiber.Map{"error": "Você não tem permissão para desassociar esta tarefa."})
@@ -728,35 +602,78 @@ func (app *App) handleUnassignContato(c *fiber.Ctx) error {
 func (app *App) setupRoutes(fiberApp *fiber.App) {
 	api := fiberApp.Group("/api")
 
+	// --- autenticacao do provedor local (sem token) ---
+	api.Post("/auth/login", app.login)
+	api.Post("/auth/refresh", app.refreshSession)
+
 	// --- TODOS os usuários autenticados ---
 	protected := api.Group("")
 	protected.Use(app.authMiddleware)
 
 	protected.Get("/users", app.getUsers)
+	protected.Get("/search", app.search)
 	protected.Post("/user/avatar", app.handleAvatarUpload)
+	protected.Get("/users/me/profile", app.getMyProfile)
+	protected.Put("/users/me/profile", app.updateMyProfile)
 
 	protected.Get("/boards/public", app.getPublicBoards)
 	protected.Get("/boards/private", app.getPrivateBoards)
 	protected.Post("/boards", app.createBoard)
 	protected.Delete("/boards/:id", app.deleteBoard)
+	protected.Post("/boards/:id/duplicate", app.duplicateBoard)
+	protected.Get("/board-templates", app.getBoardTemplates)
+	protected.Post("/board-templates", app.createBoardTemplate)
 	protected.Get("/boards/:id/columns", app.getColumns)
 	protected.Post("/boards/:id/columns/reorder", app.reorderColumns)
 	protected.Post("/columns", app.createColumn)
 	protected.Put("/columns/:id", app.updateColumn)
 	protected.Delete("/columns/:id", app.deleteColumn)
+	protected.Put("/columns/:id/policy", app.setColumnPolicy)
 	protected.Get("/columns/:id/cards", app.getCards)
 	protected.Post("/columns/:id/cards", app.createCard)
+	protected.Post("/columns/:id/archive", app.archiveColumnCards)
 	protected.Put("/cards/:id", app.updateCard)
 	protected.Delete("/cards/:id", app.deleteCard)
+	protected.Post("/cards/:id/archive", app.archiveCard)
+	protected.Post("/cards/:id/unarchive", app.unarchiveCard)
+	protected.Get("/cards/:id/recurrence", app.getCardRecurrence)
+	protected.Put("/cards/:id/recurrence", app.setCardRecurrence)
+	protected.Delete("/cards/:id/recurrence", app.deleteCardRecurrence)
 	protected.Post("/cards/move", app.moveCard)
+	protected.Get("/cards/:id/activity", app.getCardActivity)
+	protected.Get("/cards/:id/comments", app.getCardComments)
+	protected.Post("/cards/:id/comments", app.createCardComment)
+	protected.Put("/cards/:id/comments/:commentId", app.updateCardComment)
+	protected.Delete("/cards/:id/comments/:commentId", app.deleteCardComment)
+	protected.Get("/cards/:id/attachments", app.getCardAttachments)
+	protected.Post("/cards/:id/attachments", app.uploadCardAttachment)
+	protected.Get("/cards/:id/attachments/:attachmentId/download", app.downloadCardAttachment)
+	protected.Delete("/cards/:id/attachments/:attachmentId", app.deleteCardAttachment)
+
+	protected.Get("/boards/:id/labels", app.getBoardLabels)
+	protected.Post("/boards/:id/labels", app.createBoardLabel)
+	protected.Put("/boards/:id/labels/:labelId", app.updateBoardLabel)
+	protected.Delete("/boards/:id/labels/:labelId", app.deleteBoardLabel)
+	protected.Post("/cards/:id/labels/:labelId", app.addCardLabel)
+	protected.Get("/cards/:id/checklist", app.getCardChecklist)
+	protected.Post("/cards/:id/checklist", app.addChecklistItem)
+	protected.Post("/cards/:id/checklist/reorder", app.reorderChecklist)
+	protected.Post("/cards/:id/checklist/:itemId/toggle", app.toggleChecklistItem)
+	protected.Delete("/cards/:id/checklist/:itemId", app.deleteChecklistItem)
+	protected.Delete("/cards/:id/labels/:labelId", app.removeCardLabel)
 
 	protected.Get("/boards/:id/members", app.getBoardMembers)
 	protected.Get("/boards/:id/invitable-users", app.getInvitableUsers)
 	protected.Post("/boards/:id/invite", app.inviteUserToBoard)
 	protected.Post("/invitations/:id/respond", app.respondToInvitation)
 	protected.Delete("/boards/:boardId/members/:memberId", app.removeBoardMember)
+	protected.Put("/boards/:boardId/members/:memberId/role", app.updateBoardMemberRole)
+	protected.Post("/boards/:id/transfer", app.transferBoardOwnership)
 	protected.Post("/boards/:id/leave", app.leaveBoard)
 
+	protected.Get("/trash", app.getTrash)
+	protected.Post("/trash/:type/:id/restore", app.restoreTrashItem)
+
 	protected.Get("/notifications", app.getNotifications)
 	protected.Post("/notifications/:id/read", app.markNotificationRead)
 	protected.Post("/notifications/mark-all-as-read", app.markAllNotificationsRead)
@@ -776,37 +693,28 @@ func (app *App) setupRoutes(fiberApp *fiber.App) {
 	protected.Post("/contatos/unassign", app.handleUnassignContato)
 	protected.Put("/contatos/:id/anotacao", app.handleUpdateContatoAnotacao)
 
-	// --- EXCLUSIVO para Administradores ---
-	adminProtected := api.Group("")
+	protected.Get("/users/me/permissions", app.getMyPermissions)
 
-	adminProtected.Use(app.authMiddleware)
-	adminProtected.Use(app.adminMiddleware)
+	// --- por permissao ---
+	protected.Post("/ligacoes", app.requirePermission(PermLigacoesManage), app.createLigacao)
+	protected.Delete("/ligacoes/:id", app.requirePermission(PermLigacoesManage), app.deleteLigacao)
+	protected.Post("/ligacoes/:id/image", app.requirePermission(PermLigacoesManage), app.handleLigacaoImageUpload)
 
-	adminProtected.Post("/ligacoes", app.createLigacao)
-	adminProtected.Delete("/ligacoes/:id", app.deleteLigacao)
-	adminProtected.Post("/ligacoes/:id/image", app.handleLigacaoImageUpload)
+	protected.Post("/agenda/events", app.requirePermission(PermAgendaManage), app.createAgendaEvent)
+	protected.Delete("/agenda/events/:id", app.requirePermission(PermAgendaManage), app.deleteAgendaEvent)
 
-	adminProtected.Post("/agenda/events", app.createAgendaEvent)
-	adminProtected.Delete("/agenda/events/:id", app.deleteAgendaEvent)
+	protected.Post("/avaliacoes", app.requirePermission(PermAvaliacoesCreate), app.createAvaliacao)
+	protected.Delete("/avaliacoes/:id", app.requirePermission(PermAvaliacoesDelete), app.deleteAvaliacao)
 
-	adminProtected.Post("/avaliacoes", app.createAvaliacao)
-	adminProtected.Delete("/avaliacoes/:id", app.deleteAvaliacao)
+	protected.Post("/contatos/admin-assign", app.requirePermission(PermContatosDistribute), app.handleAdminAssignContato)
 
-	adminProtected.Post("/contatos/admin-assign", app.handleAdminAssignContato)
+	protected.Get("/roles", app.requirePermission(PermUsersManage), app.getRoles)
+	protected.Get("/users/:id/roles", app.requirePermission(PermUsersManage), app.getUserRoles)
+	protected.Put("/users/:id/roles", app.requirePermission(PermUsersManage), app.setUserRoles)
+	protected.Get("/users/:id/profile", app.requirePermission(PermUsersManage), app.adminGetProfile)
+	protected.Put("/users/:id/profile", app.requirePermission(PermUsersManage), app.adminUpdateProfile)
 }
 
-/* comando dar admin supabase
-UPDATE auth.users
-SET raw_user_meta_data = raw_user_meta_data || '{"is_admin": true}'::jsonb
-WHERE email = 'email@kanban.local;
-*/
-
-/* comando tirar admin supabase
-UPDATE auth.users
-SET raw_user_meta_data = raw_user_meta_data || '{"is_admin": false}'::jsonb
-WHERE email = 'email@kanban.local';
-*/
-
 func (app *App) handleUpdateContatoAnotacao(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 	contatoID := c.Params("id")
@@ -819,16 +727,7 @@ func (app *App) handleUpdateContatoAnotacao(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
 	}
 
-	query := `
-        INSERT INTO contato_status (contato_id, status, anotacao, updated_by)
-        VALUES ($1, 'pendente', $2, $3)
-        ON CONFLICT (contato_id)
-        DO UPDATE SET
-            anotacao = EXCLUDED.anotacao,
-            updated_at = NOW(),
-            updated_by = EXCLUDED.updated_by
-    `
-	_, err := app.db.Exec(context.Background(), query, contatoID, payload.Anotacao, userID)
+	err := app.contatos.SetContatoAnotacao(context.Background(), contatoID, payload.Anotacao, userID)
 	if err != nil {
 		log.Printf("Erro no auto-save da anotação para o contato %s: %v", contatoID, err)
 		return c.Status(500).JSON(fiber.Map{"error": "Falha ao salvar anotação"})
@@ -839,58 +738,11 @@ func (app *App) handleUpdateContatoAnotacao(c *fiber.Ctx) error {
 
 // endpoint users
 func (app *App) getUsers(c *fiber.Ctx) error {
-	conn, err := app.db.Acquire(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro de conexão"})
-	}
-	defer conn.Release()
-	users := make([]User, 0)
-	query := `
-        SELECT
-            id,
-            email,
-            COALESCE(raw_user_meta_data->>'username', email) as username,
-            COALESCE(raw_user_meta_data->>'avatar_url', '') as avatar,
-            created_at,
-            COALESCE(role, '') as role,
-            COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) as is_admin -- <-- ADICIONE ESTA LINHA
-        FROM auth.users ORDER BY email
-    `
-	rows, err := conn.Query(context.Background(), query)
+	users, err := app.users.ListUsers(context.Background())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar usuários"})
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var user User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Avatar, &user.CreatedAt, &user.Role, &user.IsAdmin); err != nil { // <-- ATUALIZE AQUI
-			continue
-		}
-		users = append(users, user)
-	}
-	return c.JSON(users)
-}
-
-// middleware de verificacao admim
-func (app *App) adminMiddleware(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	if userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Usuário não autenticado"})
-	}
-
-	var isAdmin bool
-	query := "SELECT COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) FROM auth.users WHERE id = $1"
-	err := app.db.QueryRow(context.Background(), query, userID).Scan(&isAdmin)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar permissões de usuário"})
-	}
-
-	if !isAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado. Esta ação requer privilégios de administrador."})
-	}
-
-	return c.Next()
+	return c.JSON(app.withProfiles(context.Background(), users))
 }
 
 // admin distribuir tarefa
@@ -910,17 +762,7 @@ func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 
 	updatedBy := c.Locals("userID").(string)
 
-	query := `
-        INSERT INTO contato_status (contato_id, status, updated_by, assigned_to)
-        VALUES ($1, 'pendente', $2, $3)
-        ON CONFLICT (contato_id)
-        DO UPDATE SET
-            assigned_to = EXCLUDED.assigned_to,
-            updated_at = NOW(),
-            updated_by = EXCLUDED.updated_by
-    `
-
-	_, err := app.db.Exec(context.Background(), query, payload.ContatoID, updatedBy, payload.AssigneeID)
+	err := app.contatos.AssignContato(context.Background(), payload.ContatoID, payload.AssigneeID, updatedBy)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert para admin assumir contato: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao salvar a atribuição no banco de dados"})
@@ -934,25 +776,10 @@ func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 
 // endpoint boards publicos
 func (app *App) getPublicBoards(c *fiber.Ctx) error {
-	query := `SELECT id, title, description, owner_id, created_at, updated_at, color, is_public
-			  FROM boards
-			  WHERE is_public = true
-			  ORDER BY created_at DESC LIMIT 1`
-	rows, err := app.db.Query(context.Background(), query)
+	boards, err := app.boards.ListPublicBoards(context.Background())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar boards públicos"})
 	}
-	defer rows.Close()
-	boards := make([]Board, 0)
-	for rows.Next() {
-		var board Board
-		if err := rows.Scan(&board.ID, &board.Title, &board.Description,
-			&board.OwnerID, &board.CreatedAt, &board.UpdatedAt,
-			&board.Color, &board.IsPublic); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board"})
-		}
-		boards = append(boards, board)
-	}
 	return c.JSON(boards)
 }
 
@@ -960,79 +787,48 @@ func (app *App) getPublicBoards(c *fiber.Ctx) error {
 func (app *App) getPrivateBoards(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 
-	ownerQuery := `SELECT id, title, description, owner_id, created_at, updated_at, color, is_public
-                   FROM boards
-                   WHERE owner_id = $1 AND is_public = false`
-
-	memberQuery := `SELECT b.id, b.title, b.description, b.owner_id, b.created_at, b.updated_at, b.color, b.is_public,
-                           u.email as owner_email, -- Buscamos o email para usar no mapa
-                           COALESCE(u.raw_user_meta_data->>'username', u.email) as owner_username -- E o username como fallback
-                    FROM boards b
-                    JOIN board_memberships bm ON b.id = bm.board_id
-                    JOIN auth.users u ON b.owner_id = u.id
-                    WHERE bm.user_id = $1 AND b.owner_id != $1`
-
-	boards := make([]Board, 0)
-	boardIDs := make(map[int]bool)
-
-	rows, err := app.db.Query(context.Background(), ownerQuery, userID)
+	owned, err := app.boards.ListOwnedBoards(context.Background(), userID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar seus boards privados"})
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var board Board
-		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID, &board.CreatedAt, &board.UpdatedAt, &board.Color, &board.IsPublic); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board privado"})
-		}
-		if !boardIDs[board.ID] {
-			boards = append(boards, board)
-			boardIDs[board.ID] = true
-		}
-	}
-	rows.Close()
-
-	rows, err = app.db.Query(context.Background(), memberQuery, userID)
+	shared, err := app.boards.ListSharedBoards(context.Background(), userID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar boards compartilhados"})
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var board Board
-		var ownerEmail string
-		var ownerUsername string
-
-		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID, &board.CreatedAt, &board.UpdatedAt, &board.Color, &board.IsPublic, &ownerEmail, &ownerUsername); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board compartilhado"})
-		}
-
-		if displayName, ok := userDisplayNameMap[ownerEmail]; ok {
-			board.OwnerName = displayName
-		} else {
-			board.OwnerName = ownerUsername
-		}
+	for i := range shared {
+		shared[i].OwnerName = app.getDisplayName(context.Background(), shared[i].OwnerID)
+	}
 
+	boards := make([]Board, 0, len(owned)+len(shared))
+	boardIDs := make(map[int]bool)
+	for _, board := range append(owned, shared...) {
 		if !boardIDs[board.ID] {
 			boards = append(boards, board)
 			boardIDs[board.ID] = true
 		}
 	}
-
 	return c.JSON(boards)
 }
 
 // endpoint criar board
 func (app *App) createBoard(c *fiber.Ctx) error {
-	var reqBoard Board
-	if err := c.BodyParser(&reqBoard); err != nil {
+	var payload struct {
+		Board
+		Template   string `json:"template"`
+		TemplateID int    `json:"template_id"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "dados inválidos"})
 	}
+	reqBoard := payload.Board
 	userID := c.Locals("userID").(string)
 	if reqBoard.Title == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "O título do quadro é obrigatório"})
 	}
+	template, ok, resp := app.boardTemplateForCreate(c, userID, payload.TemplateID, payload.Template, reqBoard.IsPublic)
+	if !ok {
+		return resp
+	}
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao iniciar transação"})
@@ -1047,20 +843,9 @@ func (app *App) createBoard(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao criar board"})
 	}
 	reqBoard.OwnerID = userID
-	defaultColumns := []struct {
-		Title string
-		Color string
-	}{
-		{"A Fazer", "#58a6ff"},
-		{"Em Andamento", "#d29922"},
-		{"Concluído", "#3fb950"},
-	}
-	colQuery := `INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`
-	for i, col := range defaultColumns {
-		if _, err := tx.Exec(context.Background(), colQuery, reqBoard.ID, col.Title, i, col.Color); err != nil {
-			log.Printf("Erro ao criar coluna padrão '%s' para o board %d: %v", col.Title, reqBoard.ID, err)
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar colunas padrão"})
-		}
+	if err := app.applyBoardStructure(context.Background(), tx, reqBoard.ID, userID, template.BoardStructure); err != nil {
+		log.Printf("Erro ao aplicar o template '%s' ao board %d: %v", template.Name, reqBoard.ID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "erro ao criar a estrutura do quadro"})
 	}
 	if err := tx.Commit(context.Background()); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao confirmar criação do board"})
@@ -1079,29 +864,10 @@ func (app *App) getColumns(c *fiber.Ctx) error {
 	if err != nil || !hasPermission {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
 	}
-	var isPublic bool
-	app.db.QueryRow(context.Background(), "SELECT is_public FROM boards WHERE id = $1", boardID).Scan(&isPublic)
-
-	if isPublic {
-		return app.getPublicBoardColumns(c, boardID)
-	}
-
-	query := `SELECT id, board_id, title, position, COALESCE(color, '#e4e6ea') as color
-			  FROM columns WHERE board_id = $1 ORDER BY position`
-	rows, err := app.db.Query(context.Background(), query, boardID)
+	columns, err := app.columns.ListColumns(context.Background(), boardID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar colunas"})
 	}
-	defer rows.Close()
-
-	columns := make([]Column, 0)
-	for rows.Next() {
-		var col Column
-		if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna"})
-		}
-		columns = append(columns, col)
-	}
 	return c.JSON(columns)
 }
 
@@ -1113,9 +879,8 @@ func (app *App) reorderColumns(c *fiber.Ctx) error {
 	}
 	userID := c.Locals("userID").(string)
 
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
 	}
 
 	var payload struct {
@@ -1125,23 +890,9 @@ func (app *App) reorderColumns(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
 	}
 
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
-	}
-	defer tx.Rollback(context.Background())
-
-	query := "UPDATE columns SET position = $1 WHERE id = $2 AND board_id = $3"
-	for i, colID := range payload.OrderedColumnIDs {
-		_, err := tx.Exec(context.Background(), query, i, colID, boardID)
-		if err != nil {
-			log.Printf("Erro ao reordenar coluna ID %d: %v", colID, err)
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar uma das colunas"})
-		}
-	}
-
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a reordenação"})
+	if err := app.columns.ReorderColumns(context.Background(), boardID, payload.OrderedColumnIDs); err != nil {
+		log.Printf("Erro ao reordenar colunas do board %d: %v", boardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar uma das colunas"})
 	}
 
 	app.broadcast(boardID, WsMessage{
@@ -1153,81 +904,6 @@ func (app *App) reorderColumns(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// pegar boards publicos
-func (app *App) getPublicBoardColumns(c *fiber.Ctx, boardID int) error {
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação de verificação"})
-	}
-	defer tx.Rollback(context.Background())
-	query := `SELECT id, board_id, title, position, COALESCE(color, '#e4e6ea') as color
-			  FROM columns WHERE board_id = $1 ORDER BY position`
-	rows, err := tx.Query(context.Background(), query, boardID)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar colunas"})
-	}
-	columns := make([]Column, 0)
-	foundSolucionado := false
-	foundNaoSolucionado := false
-	maxPosition := -1
-	for rows.Next() {
-		var col Column
-		if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-			rows.Close()
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna"})
-		}
-		titleLower := strings.ToLower(col.Title)
-		if titleLower == "solucionado" {
-			foundSolucionado = true
-		}
-		if titleLower == "não solucionado" {
-			foundNaoSolucionado = true
-		}
-		if col.Position > maxPosition {
-			maxPosition = col.Position
-		}
-		columns = append(columns, col)
-	}
-	rows.Close()
-	if !foundSolucionado {
-		maxPosition++
-		_, err = tx.Exec(context.Background(),
-			`INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`,
-			boardID, "Solucionado", maxPosition, "#3fb950")
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar coluna 'Solucionado'"})
-		}
-	}
-	if !foundNaoSolucionado {
-		maxPosition++
-		_, err = tx.Exec(context.Background(),
-			`INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`,
-			boardID, "Não Solucionado", maxPosition, "#f85149")
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar coluna 'Não Solucionado'"})
-		}
-	}
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar criação de colunas de status"})
-	}
-	if !foundSolucionado || !foundNaoSolucionado {
-		rows, err = app.db.Query(context.Background(), query, boardID)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao re-buscar colunas após correção"})
-		}
-		defer rows.Close()
-		columns = make([]Column, 0)
-		for rows.Next() {
-			var col Column
-			if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna após correção"})
-			}
-			columns = append(columns, col)
-		}
-	}
-	return c.JSON(columns)
-}
-
 // endpoint cards
 func (app *App) getCards(c *fiber.Ctx) error {
 	columnID, err := strconv.Atoi(c.Params("id"))
@@ -1243,35 +919,29 @@ func (app *App) getCards(c *fiber.Ctx) error {
 	if err != nil || !hasPermission {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
 	}
-	rows, err := app.db.Query(context.Background(), `
-		SELECT id, column_id, title, COALESCE(description, '') as description,
-			   COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
-			   due_date, position, created_at, updated_at, completed_at
-		FROM cards WHERE column_id = $1 ORDER BY position`, columnID)
+	labelIDs, err := parseLabelFilter(c.Query("label_ids"))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar cards"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Filtro de etiquetas inválido"})
 	}
-	defer rows.Close()
-	cards := make([]Card, 0)
-	for rows.Next() {
-		var card Card
-		if err := rows.Scan(&card.ID, &card.ColumnID, &card.Title, &card.Description,
-			&card.AssignedTo, &card.Priority, &card.DueDate, &card.Position,
-			&card.CreatedAt, &card.UpdatedAt, &card.CompletedAt); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados do card"})
-		}
-		cards = append(cards, card)
+	filter := CardFilter{LabelIDs: labelIDs, IncludeArchived: c.QueryBool("include_archived")}
+	cards, err := app.cards.ListCards(context.Background(), columnID, filter)
+	if err != nil {
+		log.Printf("Erro ao buscar cards da coluna %d: %v", columnID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar cards"})
 	}
 	return c.JSON(cards)
 }
 
+// card completo por id (labels e checklist inclusos)
+func (app *App) getCardByID(cardID int) (Card, error) {
+	return app.cards.GetCard(context.Background(), cardID)
+}
+
 // pegar user por id auth
 func (app *App) getUserIDByUsername(username string) (string, error) {
-	var userID string
-	query := `SELECT id FROM auth.users WHERE raw_user_meta_data->>'username' = $1 OR email = $1 LIMIT 1`
-	err := app.db.QueryRow(context.Background(), query, username).Scan(&userID)
+	userID, err := app.users.UserIDByUsername(context.Background(), username)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return "", fmt.Errorf("usuário '%s' não encontrado", username)
 		}
 		return "", err
@@ -1279,12 +949,13 @@ func (app *App) getUserIDByUsername(username string) (string, error) {
 	return userID, nil
 }
 
+const notificationInsert = `INSERT INTO notifications
+              (user_id, type, message, related_board_id, related_card_id, invitation_id)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+
 // endpoint notificacao
 func (app *App) createNotification(tx pgx.Tx, n Notification) error {
-	query := `INSERT INTO notifications 
-              (user_id, type, message, related_board_id, related_card_id, invitation_id) 
-              VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := tx.Exec(context.Background(), query,
+	_, err := tx.Exec(context.Background(), notificationInsert,
 		n.UserID, n.Type, n.Message, n.RelatedBoardID, n.RelatedCardID, n.InvitationID)
 	return err
 }
@@ -1299,48 +970,54 @@ func (app *App) createCard(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
 	}
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado"})
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
 	}
 	var card Card
 	if err := c.BodyParser(&card); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados de card inválidos"})
 	}
 	card.ColumnID = columnID
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
-	}
-	defer tx.Rollback(context.Background())
-	var maxPos sql.NullInt64
-	tx.QueryRow(context.Background(), "SELECT MAX(position) FROM cards WHERE column_id = $1", columnID).Scan(&maxPos)
-	card.Position = int(maxPos.Int64) + 1
-	query := `INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
-	err = tx.QueryRow(context.Background(), query, card.ColumnID, card.Title, card.Description, card.AssignedTo, card.Priority, card.DueDate, card.Position).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+	card, wipExceeded, err := app.cards.CreateCard(context.Background(), card, userID)
 	if err != nil {
+		var violation *ColumnPolicyViolation
+		if errors.As(err, &violation) {
+			return c.Status(fiber.StatusConflict).JSON(violation)
+		}
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
+		}
+		log.Printf("Erro ao criar card na coluna %d: %v", columnID, err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar card"})
 	}
 	if card.AssignedTo != "" {
-		assigneeID, err := app.getUserIDByUsername(card.AssignedTo)
-		if err == nil {
-			notification := Notification{
-				UserID:         assigneeID,
-				Type:           "new_task_assigned",
-				Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", card.Title),
-				RelatedBoardID: &boardID,
-				RelatedCardID:  &card.ID,
-			}
-			app.createNotification(tx, notification)
-		}
-	}
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar criação"})
+		app.notifyAssignee(boardID, card)
 	}
 	app.broadcast(boardID, WsMessage{Type: "CARD_CREATED", Payload: card})
+	if wipExceeded != nil {
+		app.broadcast(boardID, WsMessage{Type: "WIP_EXCEEDED", Payload: wipExceeded, SenderID: userID})
+	}
 	return c.Status(201).JSON(card)
 }
 
+// avisar o responsavel de que o card e dele; a notificacao nao desfaz a escrita do card
+func (app *App) notifyAssignee(boardID int, card Card) {
+	assigneeID, err := app.getUserIDByUsername(card.AssignedTo)
+	if err != nil {
+		return
+	}
+	notification := Notification{
+		UserID:         assigneeID,
+		Type:           "new_task_assigned",
+		Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", card.Title),
+		RelatedBoardID: &boardID,
+		RelatedCardID:  &card.ID,
+	}
+	if err := app.notifications.CreateNotification(context.Background(), notification); err != nil {
+		log.Printf("Erro ao notificar o responsável do card %d: %v", card.ID, err)
+	}
+}
+
 // endpoint att card
 func (app *App) updateCard(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("id"))
@@ -1348,153 +1025,91 @@ func (app *App) updateCard(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "ID do card inválido"})
 	}
 
+	userID := c.Locals("userID").(string)
+	cardBoardID, err := app.getBoardIDFromCard(cardID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Tarefa não encontrada"})
+	}
+	if ok, resp := app.requireBoardCapability(c, userID, cardBoardID, CapEdit); !ok {
+		return resp
+	}
+
 	var payload Card
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados de card inválidos"})
 	}
-
-	tx, err := app.db.Begin(context.Background())
+	existingCard, err := app.cards.UpdateCard(context.Background(), cardID, payload, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
-	}
-	defer tx.Rollback(context.Background())
-
-	var existingCard Card
-	err = tx.QueryRow(context.Background(), "SELECT column_id, assigned_to FROM cards WHERE id = $1", cardID).Scan(&existingCard.ColumnID, &existingCard.AssignedTo)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return c.Status(404).JSON(fiber.Map{"error": "Tarefa não encontrada"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar tarefa original"})
-	}
-
-	if payload.ColumnID == 0 {
-		payload.ColumnID = existingCard.ColumnID
-	}
-
-	query := `
-		UPDATE cards SET 
-			title = $1, 
-			description = $2, 
-			assigned_to = $3, 
-			priority = $4, 
-			due_date = $5, 
-			column_id = $6,
-			completed_at = $7,
-			updated_at = NOW() 
-		WHERE id = $8`
-
-	_, err = tx.Exec(context.Background(), query,
-		payload.Title, payload.Description, payload.AssignedTo, payload.Priority,
-		payload.DueDate, payload.ColumnID, payload.CompletedAt, cardID)
-
-	if err != nil {
+		// mudar de coluna so pelo /cards/move, que aplica as regras da coluna e reordena as posicoes
+		if errors.Is(err, ErrCardColumnChange) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Para mudar o card de coluna use POST /api/cards/move."})
+		}
 		log.Printf("Erro ao atualizar card no DB: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar card no banco de dados"})
 	}
 
 	if payload.AssignedTo != "" && payload.AssignedTo != existingCard.AssignedTo {
-		boardID, err := app.getBoardIDFromCard(cardID)
-		if err == nil {
-			assigneeID, err := app.getUserIDByUsername(payload.AssignedTo)
-			if err == nil {
-				notification := Notification{
-					UserID:         assigneeID,
-					Type:           "new_task_assigned",
-					Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", payload.Title),
-					RelatedBoardID: &boardID,
-					RelatedCardID:  &cardID,
-				}
-				app.createNotification(tx, notification)
-			}
-		}
+		payload.ID = cardID
+		app.notifyAssignee(cardBoardID, payload)
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar atualização"})
-	}
-
-	// Envia a atualização para outros clientes via WebSocket
-	go func() {
-		boardID, err := app.getBoardIDFromCard(cardID)
-		if err == nil {
-			var updatedCard Card
-			selectQuery := `SELECT id, column_id, title, COALESCE(description, '') as description, COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority, due_date, position, created_at, updated_at, completed_at FROM cards WHERE id = $1`
-			err := app.db.QueryRow(context.Background(), selectQuery, cardID).Scan(&updatedCard.ID, &updatedCard.ColumnID, &updatedCard.Title, &updatedCard.Description, &updatedCard.AssignedTo, &updatedCard.Priority, &updatedCard.DueDate, &updatedCard.Position, &updatedCard.CreatedAt, &updatedCard.UpdatedAt, &updatedCard.CompletedAt)
-			if err != nil {
-				log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
-				return
-			}
-			app.broadcast(boardID, WsMessage{Type: "CARD_UPDATED", Payload: updatedCard})
-		}
-	}()
+	// broadcast sincrono apos o commit, como no createCard
+	updatedCard, err := app.getCardByID(cardID)
+	if err != nil {
+		log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
+	} else {
+		app.broadcast(cardBoardID, WsMessage{Type: "CARD_UPDATED", Payload: updatedCard})
+	}
 
 	return c.Status(200).JSON(fiber.Map{"status": "updated"})
 }
 
 // permissao dos boards
 func (app *App) checkBoardPermission(userID string, boardID int) (bool, error) {
-	var isPublic bool
-	err := app.db.QueryRow(context.Background(), "SELECT is_public FROM boards WHERE id = $1", boardID).Scan(&isPublic)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
 	}
-	if isPublic {
+	if board.IsPublic || board.OwnerID == userID {
 		return true, nil
 	}
-
-	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
-		}
-		return false, err
-	}
-	if ownerID == userID {
-		return true, nil
-	}
-
-	var memberCount int
-	err = app.db.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM board_memberships WHERE board_id = $1 AND user_id = $2",
-		boardID, userID).Scan(&memberCount)
-	if err != nil {
-		return false, err
-	}
-	if memberCount > 0 {
-		return true, nil
+	_, err = app.boards.BoardMemberRole(context.Background(), boardID, userID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
 	}
-	return false, nil
+	return err == nil, err
 }
 
 // pegar id do board por coluna
 func (app *App) getBoardIDFromColumn(columnID int) (int, error) {
-	var boardID int
-	query := `SELECT board_id FROM columns WHERE id = $1`
-	err := app.db.QueryRow(context.Background(), query, columnID).Scan(&boardID)
-	if err != nil {
-		return 0, err
-	}
-	return boardID, nil
+	return app.boards.BoardIDForColumn(context.Background(), columnID)
 }
 
 // endpoint deletar card
 func (app *App) deleteCard(c *fiber.Ctx) error {
-	cardID, _ := strconv.Atoi(c.Params("id"))
-	boardID, err := app.getBoardIDFromCard(cardID)
+	cardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID do card inválido"})
 	}
-	_, err = app.db.Exec(context.Background(), `DELETE FROM cards WHERE id = $1`, cardID)
+	userID := c.Locals("userID").(string)
+	boardID, err := app.getBoardIDFromCard(cardID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao deletar card"})
+		return c.Status(404).JSON(fiber.Map{"error": "Card não encontrado"})
+	}
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
 	}
-	if boardID != 0 {
-		app.broadcast(boardID, WsMessage{Type: "CARD_DELETED", Payload: fiber.Map{"card_id": cardID}})
+	if err := app.cards.DeleteCard(context.Background(), cardID, userID); err != nil {
+		log.Printf("Erro ao deletar card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "erro ao deletar card"})
 	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_DELETED", Payload: fiber.Map{"card_id": cardID}})
 	return c.Status(200).JSON(fiber.Map{"status": "deleted"})
 }
 
@@ -1512,100 +1127,57 @@ func (app *App) moveCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
 	}
 
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
-	}
-	defer tx.Rollback(context.Background())
-
-	var oldColumnID, oldPosition int
-	var oldColumnTitle, newColumnTitle string
-
-	err = tx.QueryRow(context.Background(),
-		`SELECT c.column_id, c.position, col.title FROM cards c
-		 JOIN columns col ON c.column_id = col.id
-		 WHERE c.id = $1`, payload.CardID,
-	).Scan(&oldColumnID, &oldPosition, &oldColumnTitle)
+	sourceBoardID, err := app.getBoardIDFromCard(payload.CardID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Card não encontrado"})
 	}
-
-	err = tx.QueryRow(context.Background(), "SELECT title FROM columns WHERE id = $1", payload.NewColumnID).Scan(&newColumnTitle)
+	targetBoardID, err := app.getBoardIDFromColumn(payload.NewColumnID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Coluna de destino não encontrada"})
 	}
+	for _, boardID := range []int{sourceBoardID, targetBoardID} {
+		if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+			return resp
+		}
+	}
 
-	_, err = tx.Exec(context.Background(),
-		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2",
-		oldColumnID, oldPosition,
-	)
+	move, err := app.cards.MoveCard(context.Background(), payload.CardID, payload.NewColumnID, payload.NewPosition, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coluna antiga"})
+		var violation *ColumnPolicyViolation
+		switch {
+		case errors.As(err, &violation):
+			return c.Status(fiber.StatusConflict).JSON(violation)
+		case errors.Is(err, ErrCardArchived):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "O card está arquivado. Desarquive antes de mover."})
+		case errors.Is(err, ErrNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "Card ou coluna de destino não encontrados"})
+		}
+		log.Printf("Erro ao mover o card %d: %v", payload.CardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mover o card"})
 	}
 
-	_, err = tx.Exec(context.Background(),
-		"UPDATE cards SET position = position + 1 WHERE column_id = $1 AND position >= $2",
-		payload.NewColumnID, payload.NewPosition,
-	)
+	updatedCard, err := app.getCardByID(payload.CardID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar nova coluna"})
+		log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
+		return c.SendStatus(fiber.StatusNoContent)
 	}
-
-	isEnteringFinalColumn := strings.ToLower(newColumnTitle) == "solucionado" || strings.ToLower(newColumnTitle) == "não solucionado"
-	isLeavingFinalColumn := strings.ToLower(oldColumnTitle) == "solucionado" || strings.ToLower(oldColumnTitle) == "não solucionado"
-
-	completedAtUpdateQuery := ""
-	if isEnteringFinalColumn && !isLeavingFinalColumn {
-		completedAtUpdateQuery = ", completed_at = NOW()"
-	} else if isLeavingFinalColumn && !isEnteringFinalColumn {
-		completedAtUpdateQuery = ", completed_at = NULL"
+	moved := WsMessage{
+		SenderID: userID,
+		Type:     "CARD_MOVED",
+		Payload: fiber.Map{
+			"card":          updatedCard,
+			"old_column_id": move.OldColumnID,
+		},
 	}
-
-	updateQuery := fmt.Sprintf(
-		"UPDATE cards SET column_id = $1, position = $2, updated_at = NOW() %s WHERE id = $3",
-		completedAtUpdateQuery,
-	)
-	_, err = tx.Exec(context.Background(), updateQuery, payload.NewColumnID, payload.NewPosition, payload.CardID)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mover o card"})
+	app.broadcast(targetBoardID, moved)
+	// o board de origem tambem precisa saber que o card saiu
+	if sourceBoardID != targetBoardID {
+		app.broadcast(sourceBoardID, moved)
 	}
-
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a movimentação"})
+	if move.WipExceeded != nil {
+		app.broadcast(targetBoardID, WsMessage{SenderID: userID, Type: "WIP_EXCEEDED", Payload: move.WipExceeded})
 	}
 
-	go func() {
-		var updatedCard Card
-		selectQuery := `
-			SELECT id, column_id, title, COALESCE(description, '') as description,
-				   COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
-				   due_date, position, created_at, updated_at, completed_at
-			FROM cards WHERE id = $1`
-
-		err := app.db.QueryRow(context.Background(), selectQuery, payload.CardID).Scan(
-			&updatedCard.ID, &updatedCard.ColumnID, &updatedCard.Title, &updatedCard.Description,
-			&updatedCard.AssignedTo, &updatedCard.Priority, &updatedCard.DueDate, &updatedCard.Position,
-			&updatedCard.CreatedAt, &updatedCard.UpdatedAt, &updatedCard.CompletedAt,
-		)
-
-		if err != nil {
-			log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
-			return
-		}
-
-		boardID, boardIDErr := app.getBoardIDFromColumn(payload.NewColumnID)
-		if boardIDErr == nil && boardID != 0 {
-			app.broadcast(boardID, WsMessage{
-				SenderID: userID,
-				Type:     "CARD_MOVED",
-				Payload: fiber.Map{
-					"card":          updatedCard,
-					"old_column_id": oldColumnID,
-				},
-			})
-		}
-	}()
-
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -1617,23 +1189,22 @@ func (app *App) leaveBoard(c *fiber.Ctx) error {
 	}
 	userID := c.Locals("userID").(string)
 
-	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar o quadro"})
 	}
 
-	if ownerID == userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "O dono do quadro não pode sair. Use a opção de excluir o quadro."})
+	if board.OwnerID == userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "O dono do quadro não pode sair. Transfira o quadro para outro membro ou exclua-o."})
 	}
 
-	_, err = app.db.Exec(context.Background(), "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, userID)
-	if err != nil {
+	if err := app.boards.RemoveBoardMember(context.Background(), boardID, userID); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Falha ao sair do quadro."})
 	}
+	app.hub.disconnectUser(boardID, userID)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -1645,53 +1216,65 @@ func (app *App) getInvitableUsers(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de quadro inválido"})
 	}
 	currentUserID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, currentUserID, boardID, CapManage); !ok {
+		return resp
+	}
 
-	query := `
-		SELECT 
-			u.id, 
-			u.email, 
-			COALESCE(u.raw_user_meta_data->>'username', u.email) as username, 
-			COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar
-		FROM auth.users u
-		LEFT JOIN boards b ON b.id = $1 AND u.id = b.owner_id
-		LEFT JOIN board_memberships bm ON bm.board_id = $1 AND u.id = bm.user_id
-		WHERE 
-			u.id != $2           -- Exclui o próprio usuário da lista
-			AND b.owner_id IS NULL       -- Garante que não é o dono do quadro
-			AND bm.user_id IS NULL       -- Garante que não é um membro atual
-		ORDER BY COALESCE(u.raw_user_meta_data->>'username', u.email)
-	`
-
-	rows, err := app.db.Query(context.Background(), query, boardID, currentUserID)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar usuários"})
+	}
+	members, err := app.boards.ListBoardMembers(context.Background(), boardID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do quadro %d: %v", boardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar usuários"})
+	}
+	users, err := app.users.ListUsers(context.Background())
 	if err != nil {
 		log.Printf("Erro ao buscar usuários convidáveis: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar usuários"})
 	}
-	defer rows.Close()
 
+	// fora: o proprio usuario, o dono, quem ja e membro e perfis inativos
+	excluded := map[string]bool{currentUserID: true, board.OwnerID: true}
+	for _, m := range members {
+		excluded[m.UserID] = true
+	}
 	invitableUsers := make([]User, 0)
-	for rows.Next() {
-		var user User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Avatar); err == nil {
+	for _, user := range app.withProfiles(context.Background(), users) {
+		if !excluded[user.ID] && user.Active {
 			invitableUsers = append(invitableUsers, user)
-		} else {
-			log.Printf("Erro ao escanear usuário convidável: %v", err)
 		}
 	}
+	sort.SliceStable(invitableUsers, func(i, j int) bool { return invitableUsers[i].DisplayName < invitableUsers[j].DisplayName })
 
 	return c.JSON(invitableUsers)
 }
 
 // convidar user
 func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
-	boardID, _ := strconv.Atoi(c.Params("id"))
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de quadro inválido"})
+	}
 	inviterID := c.Locals("userID").(string)
+	if ok, resp := app.requireBoardCapability(c, inviterID, boardID, CapManage); !ok {
+		return resp
+	}
 	var payload struct {
 		InviteeID string `json:"invitee_id"`
+		Role      string `json:"role"`
 	}
-	if err := c.BodyParser(&payload); err != nil {
+	if err := c.BodyParser(&payload); err != nil || payload.InviteeID == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
 	}
+	role, ok := parseMemberRole(payload.Role)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Papel inválido. Use editor, commenter ou viewer."})
+	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
@@ -1701,16 +1284,17 @@ func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
 
 	var invID int
 	upsertQuery := `
-		INSERT INTO board_invitations (board_id, inviter_id, invitee_id, status, created_at, updated_at)
-		VALUES ($1, $2, $3, 'pending', NOW(), NOW())
+		INSERT INTO board_invitations (board_id, inviter_id, invitee_id, role, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'pending', NOW(), NOW())
 		ON CONFLICT (board_id, invitee_id) -- Agora o PostgreSQL entende esta linha
 		DO UPDATE SET 
 			status = 'pending', 
 			inviter_id = EXCLUDED.inviter_id, 
+			role = EXCLUDED.role,
 			updated_at = NOW()
 		RETURNING id
 	`
-	err = tx.QueryRow(context.Background(), upsertQuery, boardID, inviterID, payload.InviteeID).Scan(&invID)
+	err = tx.QueryRow(context.Background(), upsertQuery, boardID, inviterID, payload.InviteeID, role).Scan(&invID)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert do convite: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar ou reativar o convite"})
@@ -1718,7 +1302,7 @@ func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
 
 	var boardTitle string
 	tx.QueryRow(context.Background(), "SELECT title FROM boards WHERE id = $1", boardID).Scan(&boardTitle)
-	inviterName := app.getDisplayName(context.Background(), tx, inviterID)
+	inviterName := app.getDisplayName(context.Background(), inviterID)
 
 	notification := Notification{
 		UserID:         payload.InviteeID,
@@ -1735,7 +1319,7 @@ func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar convite"})
 	}
 
-	return c.Status(201).JSON(fiber.Map{"status": "invited"})
+	return c.Status(201).JSON(fiber.Map{"status": "invited", "role": role})
 }
 
 // responder convite
@@ -1764,14 +1348,15 @@ func (app *App) respondToInvitation(c *fiber.Ctx) error {
 	defer tx.Rollback(context.Background())
 
 	var boardID int
+	var role BoardRole
 	status := "rejected"
 	if payload.Accept {
 		status = "accepted"
 	}
 
 	err = tx.QueryRow(context.Background(),
-		"UPDATE board_invitations SET status = $1, updated_at = now() WHERE id = $2 AND invitee_id = $3 AND status = 'pending' RETURNING board_id",
-		status, invitationID, userID).Scan(&boardID)
+		"UPDATE board_invitations SET status = $1, updated_at = now() WHERE id = $2 AND invitee_id = $3 AND status = 'pending' RETURNING board_id, role",
+		status, invitationID, userID).Scan(&boardID, &role)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -1783,8 +1368,8 @@ func (app *App) respondToInvitation(c *fiber.Ctx) error {
 
 	if payload.Accept {
 		_, err = tx.Exec(context.Background(),
-			"INSERT INTO board_memberships (board_id, user_id) VALUES ($1, $2) ON CONFLICT (board_id, user_id) DO NOTHING",
-			boardID, userID)
+			"INSERT INTO board_memberships (board_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (board_id, user_id) DO UPDATE SET role = EXCLUDED.role",
+			boardID, userID, role)
 
 		if err != nil {
 			log.Printf("[RESPOND_INVITE] Erro CRÍTICO ao inserir em board_memberships (passo 4): %v", err)
@@ -1794,7 +1379,7 @@ func (app *App) respondToInvitation(c *fiber.Ctx) error {
 		var ownerID string
 		var boardTitle string
 		tx.QueryRow(context.Background(), "SELECT owner_id, title FROM boards WHERE id = $1", boardID).Scan(&ownerID, &boardTitle)
-		inviteeName := app.getDisplayName(context.Background(), tx, userID)
+		inviteeName := app.getDisplayName(context.Background(), userID)
 
 		if ownerID != "" && inviteeName != "" {
 			ownerNotification := Notification{
@@ -1875,34 +1460,31 @@ func (app *App) deleteLigacao(c *fiber.Ctx) error {
 }
 
 func (app *App) handleLigacaoImageUpload(c *fiber.Ctx) error {
-	ligacaoID, _ := strconv.Atoi(c.Params("id"))
+	ligacaoID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da ligação inválido"})
+	}
 	file, err := c.FormFile("image")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nenhum arquivo enviado"})
 	}
 
-	src, _ := file.Open()
-	defer src.Close()
-	fileBytes, _ := io.ReadAll(src)
-
-	fileName := fmt.Sprintf("ligacao-%d%s", ligacaoID, filepath.Ext(file.Filename))
-	supabaseURL := os.Getenv("SUPABASE_PROJECT_URL")
-	supabaseKey := os.Getenv("SUPABASE_SERVICE_KEY")
-
-	uploadURL := fmt.Sprintf("%s/storage/v1/object/ligacoes/%s", supabaseURL, fileName)
-	req, _ := http.NewRequest("POST", uploadURL, bytes.NewReader(fileBytes))
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", file.Header.Get("Content-Type"))
-	req.Header.Set("x-upsert", "true")
+	fileBytes, err := readFormFile(file)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao ler o arquivo"})
+	}
+	contentType, ext, ok := sniffImage(fileBytes)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Formato de arquivo inválido. Envie uma imagem JPEG, PNG, GIF ou WebP."})
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
+	fileName := fmt.Sprintf("ligacao-%d%s", ligacaoID, ext)
+	publicURL, err := app.storage.Put(context.Background(), "ligacoes", fileName, contentType, fileBytes)
+	if err != nil {
+		log.Printf("❌ Erro ao armazenar imagem da ligação: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Falha ao armazenar o arquivo"})
 	}
-	defer resp.Body.Close()
 
-	publicURL := fmt.Sprintf("%s/storage/v1/object/public/ligacoes/%s", supabaseURL, fileName)
 	_, err = app.db.Exec(context.Background(), "UPDATE ligacoes SET image_url=$1 WHERE id=$2", publicURL, ligacaoID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar URL da imagem no banco"})
@@ -1918,56 +1500,50 @@ func (app *App) getBoardMembers(c *fiber.Ctx) error {
 	if err != nil || !hasPermission {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado."})
 	}
-	query := `(SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, true as is_owner FROM auth.users u JOIN boards b ON u.id = b.owner_id WHERE b.id = $1) UNION (SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, false as is_owner FROM auth.users u JOIN board_memberships bm ON u.id = bm.user_id WHERE bm.board_id = $1 AND u.id NOT IN (SELECT owner_id FROM boards WHERE id = $1)) ORDER BY is_owner DESC, username;`
-	rows, err := app.db.Query(context.Background(), query, boardID)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar membros"})
+	}
+	memberships, err := app.boards.ListBoardMembers(context.Background(), boardID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar membros"})
 	}
-	defer rows.Close()
 	type Member struct {
 		User
-		IsOwner bool `json:"is_owner"`
-	}
-	members := make([]Member, 0)
-	for rows.Next() {
-		var member Member
-		if err := rows.Scan(&member.ID, &member.Email, &member.Username, &member.Avatar, &member.IsOwner); err == nil {
-			members = append(members, member)
+		IsOwner bool      `json:"is_owner"`
+		Role    BoardRole `json:"role"`
+	}
+	roles := map[string]BoardRole{board.OwnerID: BoardRoleOwner}
+	users := make([]User, 0, len(memberships)+1)
+	for _, m := range append([]BoardMembership{{UserID: board.OwnerID, Role: BoardRoleOwner}}, memberships...) {
+		roles[m.UserID] = m.Role
+		if user, err := app.users.GetUser(context.Background(), m.UserID); err == nil {
+			users = append(users, user)
 		}
 	}
+	users = app.withProfiles(context.Background(), users)
+	members := make([]Member, 0, len(users))
+	for _, user := range users {
+		members = append(members, Member{User: user, IsOwner: user.ID == board.OwnerID, Role: roles[user.ID]})
+	}
+	// dono primeiro, depois por nome
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].IsOwner != members[j].IsOwner {
+			return members[i].IsOwner
+		}
+		return members[i].DisplayName < members[j].DisplayName
+	})
 	return c.JSON(members)
 }
 
 // pegar notificacoes
 func (app *App) getNotifications(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
-
-	query := `
-		SELECT 
-			n.id, n.user_id, n.type, n.message, n.is_read, n.related_board_id, n.related_card_id, 
-			n.invitation_id, n.created_at,
-			COALESCE(bi.status, '') as invitation_status
-		FROM notifications n
-		LEFT JOIN board_invitations bi ON n.invitation_id = bi.id
-		WHERE n.user_id = $1
-		ORDER BY n.is_read ASC, n.created_at DESC
-	`
-	rows, err := app.db.Query(context.Background(), query, userID)
+	notifications, err := app.notifications.ListNotifications(context.Background(), userID)
 	if err != nil {
 		log.Printf("Erro ao buscar notificações com join: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar notificações"})
 	}
-	defer rows.Close()
-
-	notifications := make([]Notification, 0)
-	for rows.Next() {
-		var n Notification
-		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &n.IsRead, &n.RelatedBoardID, &n.RelatedCardID, &n.InvitationID, &n.CreatedAt, &n.InvitationStatus); err == nil {
-			notifications = append(notifications, n)
-		} else {
-			log.Printf("Erro ao escanear notificação: %v", err)
-		}
-	}
 	return c.JSON(notifications)
 }
 
@@ -1975,8 +1551,7 @@ func (app *App) getNotifications(c *fiber.Ctx) error {
 func (app *App) markNotificationRead(c *fiber.Ctx) error {
 	notificationID, _ := strconv.Atoi(c.Params("id"))
 	userID := c.Locals("userID").(string)
-	_, err := app.db.Exec(context.Background(), "UPDATE notifications SET is_read = true WHERE id = $1 AND user_id = $2", notificationID, userID)
-	if err != nil {
+	if err := app.notifications.MarkNotificationRead(context.Background(), notificationID, userID); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao marcar notificação como lida"})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
@@ -1988,15 +1563,13 @@ func (app *App) markAllNotificationsRead(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "ID do usuário não pôde ser verificado"})
 	}
 
-	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE AND type != 'board_invitation'`
-
-	cmdTag, err := app.db.Exec(context.Background(), query, userID)
+	updated, err := app.notifications.MarkAllNotificationsRead(context.Background(), userID)
 	if err != nil {
 		log.Printf("❌ Erro ao marcar todas as notificações como lidas para o usuário %s: %v", userID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao atualizar as notificações"})
 	}
 
-	log.Printf("Notificações marcadas como lidas para o usuário %s: %d", userID, cmdTag.RowsAffected())
+	log.Printf("Notificações marcadas como lidas para o usuário %s: %d", userID, updated)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2010,24 +1583,23 @@ func (app *App) removeBoardMember(c *fiber.Ctx) error {
 	memberIdToRemove := c.Params("memberId")
 	currentUserID := c.Locals("userID").(string)
 
-	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
+	board, err := app.boards.GetBoard(context.Background(), boardID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
 	}
 
-	if ownerID != currentUserID {
+	if board.OwnerID != currentUserID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas o dono do quadro pode remover membros."})
 	}
 
-	if ownerID == memberIdToRemove {
+	if board.OwnerID == memberIdToRemove {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O dono do quadro não pode ser removido."})
 	}
 
-	_, err = app.db.Exec(context.Background(), "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, memberIdToRemove)
-	if err != nil {
+	if err := app.boards.RemoveBoardMember(context.Background(), boardID, memberIdToRemove); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Falha ao remover o membro do banco de dados."})
 	}
+	app.hub.disconnectUser(boardID, memberIdToRemove)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2196,29 +1768,91 @@ func isPortInUse(port string) bool {
 	return false
 }
 
+// app Fiber com middlewares, API e websocket (sem os arquivos estaticos)
+func (app *App) newFiberApp() *fiber.App {
+	fiberApp := fiber.New(fiber.Config{
+		// folga para o multipart acima do tamanho maximo do anexo
+		BodyLimit: int(attachmentMaxBytes()) + 1<<20,
+	})
+	fiberApp.Use(logger.New(), recover.New())
+	fiberApp.Use(cors.New(cors.Config{
+		AllowOrigins:     "http://10.0.30.251:10000, http://localhost:10000",
+		AllowCredentials: true,
+		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+	}))
+	app.setupRoutes(fiberApp)
+	fiberApp.Get("/ws/board/:id", app.wsAuthMiddleware, websocket.New(app.handleWebSocket, websocket.Config{
+		Subprotocols: []string{wsTokenSubprotocol},
+	}))
+	return fiberApp
+}
+
 // MAIN
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Arquivo .env não encontrado, usando variáveis de ambiente do sistema.")
 	}
 
-	app := &App{clients: make(map[int]map[*websocket.Conn]bool)}
+	app := &App{hub: newHub()}
 
 	if err := app.connectDB(); err != nil {
 		log.Fatalf("Falha ao conectar ao banco de dados: %v", err)
 	}
 	defer app.db.Close()
+	app.useStores(&PgStore{db: app.db})
 
-	fiberApp := fiber.New()
-	fiberApp.Use(logger.New(), recover.New())
-	fiberApp.Use(cors.New(cors.Config{
-		AllowOrigins:     "http://10.0.30.251:10000, http://localhost:10000",
-		AllowCredentials: true,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
-	}))
-	app.setupRoutes(fiberApp)
-	fiberApp.Get("/ws/board/:id", websocket.New(app.handleWebSocket))
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.runMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Falha na migração: %v", err)
+		}
+		return
+	}
+
+	if autoMigrateEnabled() {
+		if err := app.migrateUp(context.Background()); err != nil {
+			log.Fatalf("Falha ao aplicar migrações: %v", err)
+		}
+	}
+
+	identity, err := newIdentityFromEnv(app.db)
+	if err != nil {
+		log.Fatalf("Falha ao configurar o provedor de identidade: %v", err)
+	}
+	app.useIdentity(identity)
+
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if err := app.runUserCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Falha no comando user: %v", err)
+		}
+		return
+	}
+	go app.purgeBoardEvents(context.Background())
+
+	storage, err := newStorageFromEnv()
+	if err != nil {
+		log.Fatalf("Falha ao configurar o armazenamento de arquivos: %v", err)
+	}
+	app.storage = storage
+	go app.purgeTrash(context.Background())
+	go app.autoArchiveCards(context.Background())
+	go app.runRecurringCards(context.Background())
+	go app.remindDueCards(context.Background())
+
+	if os.Getenv("REALTIME_FANOUT") == "postgres" {
+		pgRelay := newPgRelay(app.db, app.hub)
+		app.hub.relay = pgRelay
+		go pgRelay.run(context.Background())
+		log.Println("Realtime: fan-out entre instâncias via LISTEN/NOTIFY ativado")
+	}
+
+	fiberApp := app.newFiberApp()
+
+	if local, ok := app.storage.(*LocalStorage); ok {
+		// apenas buckets publicos; anexos sao baixados pela API
+		fiberApp.Static(local.PublicPrefix+"/avatars", filepath.Join(local.Root, "avatars"))
+		fiberApp.Static(local.PublicPrefix+"/ligacoes", filepath.Join(local.Root, "ligacoes"))
+	}
 
 	fiberApp.Static("/", "./react-frontend/dist")
 
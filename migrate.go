@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// chave do pg_advisory_lock que serializa as migracoes entre instancias
+const migrationLockKey int64 = 7_240_311_011
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// estrutura migration
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// estado de uma migracao para o migrate status
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// carregar e validar os arquivos embutidos
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("nome de migração inválido: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("versão %d usada por duas migrações: %s e %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migração %04d_%s sem arquivo .up.sql", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// conexao dedicada com o advisory lock; o lock e da sessao, por isso nao usar o pool direto
+func (app *App) withMigrationLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	poolConn, err := app.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao obter conexão para migrações: %w", err)
+	}
+	defer poolConn.Release()
+	conn := poolConn.Conn()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("erro ao obter lock de migrações: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("erro ao criar schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// aplicar a migracao e registrar a versao na mesma transacao
+func runMigration(ctx context.Context, conn *pgx.Conn, mig Migration, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if up {
+		if _, err := tx.Exec(ctx, mig.Up); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(ctx, mig.Down); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// aplicar todas as migracoes pendentes, em ordem
+func (app *App) migrateUp(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return app.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("erro ao ler migrações aplicadas: %w", err)
+		}
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig, true); err != nil {
+				return fmt.Errorf("erro na migração %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Printf("Migração aplicada: %04d_%s", mig.Version, mig.Name)
+		}
+		return nil
+	})
+}
+
+// reverter as ultimas n migracoes aplicadas
+func (app *App) migrateDown(ctx context.Context, n int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return app.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("erro ao ler migrações aplicadas: %w", err)
+		}
+		// confere tudo antes de reverter a primeira, para nao parar no meio
+		targets := make([]Migration, 0)
+		for i := len(migrations) - 1; i >= 0 && len(targets) < n; i-- {
+			mig := migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migração %04d_%s não pode ser revertida (sem .down.sql)", mig.Version, mig.Name)
+			}
+			targets = append(targets, mig)
+		}
+		for _, mig := range targets {
+			if err := runMigration(ctx, conn, mig, false); err != nil {
+				return fmt.Errorf("erro ao reverter %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Printf("Migração revertida: %04d_%s", mig.Version, mig.Name)
+		}
+		return nil
+	})
+}
+
+// situacao de cada migracao conhecida
+func (app *App) migrateStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	err = app.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			s := MigrationStatus{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// subcomando: taskhub migrate up | down [n] | status
+func (app *App) runMigrateCommand(args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return errors.New("uso: migrate up | down [n] | status")
+	}
+	switch args[0] {
+	case "up":
+		return app.migrateUp(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("quantidade inválida: %s", args[1])
+			}
+			n = parsed
+		}
+		return app.migrateDown(ctx, n)
+	case "status":
+		statuses, err := app.migrateStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pendente"
+			if s.AppliedAt != nil {
+				state = "aplicada em " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%-24s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("subcomando de migrate desconhecido: %s", args[0])
+	}
+}
+
+// AUTO_MIGRATE=false desliga a aplicacao automatica no start
+func autoMigrateEnabled() bool {
+	return os.Getenv("AUTO_MIGRATE") != "false"
+}
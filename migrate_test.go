@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("carregar migrações: %v", err)
+	}
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			t.Fatalf("versões deveriam ser contínuas: %04d_%s na posição %d", mig.Version, mig.Name, i)
+		}
+		// a baseline cria as tabelas principais e nao tem volta; as demais precisam de .down.sql
+		if irreversible := mig.Version == 1; irreversible != (mig.Down == "") {
+			t.Fatalf("%04d_%s: down.sql inesperado (vazio=%v)", mig.Version, mig.Name, mig.Down == "")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+-- Esquema original do TaskHub. Usa IF NOT EXISTS para ser um no-op nos bancos
+-- do Supabase que ja tinham essas tabelas criadas manualmente.
+
+-- stand-in de auth.users para Postgres sem Supabase (dev e testes)
+CREATE SCHEMA IF NOT EXISTS auth;
+
+CREATE TABLE IF NOT EXISTS auth.users (
+    id                 UUID PRIMARY KEY,
+    email              TEXT UNIQUE,
+    role               TEXT,
+    raw_user_meta_data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS boards (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id    UUID NOT NULL,
+    is_public   BOOLEAN NOT NULL DEFAULT FALSE,
+    color       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS columns (
+    id       SERIAL PRIMARY KEY,
+    board_id INTEGER NOT NULL REFERENCES boards (id) ON DELETE CASCADE,
+    title    TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    color    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS columns_board_id_idx ON columns (board_id, position);
+
+CREATE TABLE IF NOT EXISTS cards (
+    id           SERIAL PRIMARY KEY,
+    column_id    INTEGER NOT NULL REFERENCES columns (id) ON DELETE CASCADE,
+    title        TEXT NOT NULL,
+    description  TEXT,
+    assigned_to  TEXT,
+    priority     TEXT DEFAULT 'media',
+    due_date     TIMESTAMPTZ,
+    position     INTEGER NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS cards_column_id_idx ON cards (column_id, position);
+
+CREATE TABLE IF NOT EXISTS board_memberships (
+    board_id   INTEGER NOT NULL REFERENCES boards (id) ON DELETE CASCADE,
+    user_id    UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (board_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS board_invitations (
+    id         SERIAL PRIMARY KEY,
+    board_id   INTEGER NOT NULL REFERENCES boards (id) ON DELETE CASCADE,
+    inviter_id UUID NOT NULL,
+    invitee_id UUID NOT NULL,
+    status     TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (board_id, invitee_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id               SERIAL PRIMARY KEY,
+    user_id          UUID NOT NULL,
+    type             TEXT NOT NULL,
+    message          TEXT NOT NULL,
+    is_read          BOOLEAN NOT NULL DEFAULT FALSE,
+    related_board_id INTEGER REFERENCES boards (id) ON DELETE CASCADE,
+    related_card_id  INTEGER REFERENCES cards (id) ON DELETE SET NULL,
+    invitation_id    INTEGER REFERENCES board_invitations (id) ON DELETE CASCADE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, is_read, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS ligacoes (
+    id              SERIAL PRIMARY KEY,
+    name            TEXT NOT NULL,
+    type            TEXT NOT NULL,
+    image_url       TEXT,
+    status          TEXT NOT NULL DEFAULT '',
+    spreadsheet_url TEXT,
+    address         TEXT,
+    end_date        TIMESTAMPTZ,
+    observations    TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS agenda_events (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT,
+    event_date  TIMESTAMPTZ NOT NULL,
+    color       TEXT NOT NULL DEFAULT '',
+    user_id     UUID,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS avaliacoes (
+    id               SERIAL PRIMARY KEY,
+    source           TEXT NOT NULL,
+    customer_name    TEXT NOT NULL,
+    review_content   TEXT NOT NULL DEFAULT '',
+    rating           INTEGER,
+    status           TEXT NOT NULL DEFAULT '',
+    review_date      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    review_url       TEXT,
+    assigned_to      TEXT,
+    resolution_notes TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS contato_status (
+    id          SERIAL PRIMARY KEY,
+    contato_id  TEXT NOT NULL UNIQUE,
+    status      TEXT NOT NULL DEFAULT 'pendente',
+    anotacao    TEXT,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by  UUID,
+    assigned_to UUID
+);
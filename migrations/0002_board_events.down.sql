@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS board_events;
+DROP TABLE IF EXISTS board_event_seqs;
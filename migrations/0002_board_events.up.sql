@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS board_event_seqs (
+    board_id INTEGER PRIMARY KEY,
+    last_seq BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS board_events (
+    board_id   INTEGER NOT NULL,
+    seq        BIGINT NOT NULL,
+    message_id TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    sender_id  TEXT,
+    payload    JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (board_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS board_events_created_at_idx ON board_events (created_at);
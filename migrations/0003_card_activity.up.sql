@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS card_activity (
+    id         BIGSERIAL PRIMARY KEY,
+    card_id    INTEGER NOT NULL,
+    board_id   INTEGER NOT NULL,
+    actor_id   TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    field      TEXT,
+    old_value  TEXT,
+    new_value  TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_activity_card_id_idx ON card_activity (card_id, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS card_comments (
+    id         SERIAL PRIMARY KEY,
+    card_id    INTEGER NOT NULL REFERENCES cards (id) ON DELETE CASCADE,
+    author_id  UUID NOT NULL,
+    text       TEXT NOT NULL,
+    edited     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_comments_card_id_idx ON card_comments (card_id, created_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS card_attachments (
+    id           SERIAL PRIMARY KEY,
+    card_id      INTEGER NOT NULL REFERENCES cards (id) ON DELETE CASCADE,
+    file_name    TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes   BIGINT NOT NULL,
+    storage_key  TEXT NOT NULL UNIQUE,
+    uploaded_by  UUID NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_attachments_card_id_idx ON card_attachments (card_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS board_labels (
+    id         SERIAL PRIMARY KEY,
+    board_id   INTEGER NOT NULL REFERENCES boards (id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    color      TEXT NOT NULL DEFAULT '#8b949e',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS board_labels_board_name_idx ON board_labels (board_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS card_labels (
+    card_id  INTEGER NOT NULL REFERENCES cards (id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES board_labels (id) ON DELETE CASCADE,
+    PRIMARY KEY (card_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS card_labels_label_id_idx ON card_labels (label_id);
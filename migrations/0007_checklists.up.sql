@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS card_checklist_items (
+    id         SERIAL PRIMARY KEY,
+    card_id    INTEGER NOT NULL REFERENCES cards (id) ON DELETE CASCADE,
+    text       TEXT NOT NULL,
+    position   INTEGER NOT NULL,
+    done       BOOLEAN NOT NULL DEFAULT FALSE,
+    done_by    UUID,
+    done_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_checklist_items_card_id_idx ON card_checklist_items (card_id, position);
@@ -0,0 +1,4 @@
+ALTER TABLE ligacoes DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE avaliacoes DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE contato_status DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE cards DROP COLUMN IF EXISTS search_vector;
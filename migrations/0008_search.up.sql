@@ -0,0 +1,25 @@
+-- busca textual (portugues) em cards, contatos, avaliacoes e ligacoes
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('portuguese', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('portuguese', coalesce(description, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS cards_search_idx ON cards USING GIN (search_vector);
+
+ALTER TABLE contato_status ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('portuguese', coalesce(anotacao, ''))) STORED;
+CREATE INDEX IF NOT EXISTS contato_status_search_idx ON contato_status USING GIN (search_vector);
+
+ALTER TABLE avaliacoes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('portuguese', coalesce(customer_name, '')), 'A') ||
+        setweight(to_tsvector('portuguese', coalesce(review_content, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS avaliacoes_search_idx ON avaliacoes USING GIN (search_vector);
+
+ALTER TABLE ligacoes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('portuguese', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('portuguese', coalesce(observations, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS ligacoes_search_idx ON ligacoes USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS local_refresh_tokens;
+DROP TABLE IF EXISTS local_users;
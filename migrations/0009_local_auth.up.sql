@@ -0,0 +1,22 @@
+-- usuarios do provedor de identidade embutido (AUTH_PROVIDER=local)
+CREATE TABLE IF NOT EXISTS local_users (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email         TEXT NOT NULL UNIQUE,
+    username      TEXT UNIQUE,
+    password_hash TEXT NOT NULL,
+    avatar_url    TEXT NOT NULL DEFAULT '',
+    role          TEXT NOT NULL DEFAULT '',
+    is_admin      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- refresh tokens guardados so como hash; cada uso gera um novo e revoga o anterior
+CREATE TABLE IF NOT EXISTS local_refresh_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES local_users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS local_refresh_tokens_user_id_idx ON local_refresh_tokens (user_id);
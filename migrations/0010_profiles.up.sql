@@ -0,0 +1,34 @@
+-- perfil de exibicao dos usuarios, independente do provedor de identidade
+CREATE TABLE IF NOT EXISTS profiles (
+    user_id      UUID PRIMARY KEY,
+    display_name TEXT NOT NULL DEFAULT '',
+    avatar_url   TEXT NOT NULL DEFAULT '',
+    phone        TEXT NOT NULL DEFAULT '',
+    team         TEXT NOT NULL DEFAULT '',
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- nomes que antes ficavam fixos no codigo (userDisplayNameMap)
+INSERT INTO profiles (user_id, display_name)
+SELECT u.id, m.display_name
+FROM (
+    SELECT id, email FROM auth.users
+    UNION ALL
+    SELECT id, email FROM local_users
+) u
+JOIN (VALUES
+    ('eduardo@kanban.local', 'Eduardo Tomaz'),
+    ('alison@kanban.local', 'Alison Silva'),
+    ('marques@kanban.local', 'Gabriel Marques'),
+    ('rosa@kanban.local', 'Gabriel Rosa'),
+    ('miyake@kanban.local', 'João Miyake'),
+    ('gomes@kanban.local', 'João Gomes'),
+    ('rodrigo@kanban.local', 'Rodrigo Akira'),
+    ('rubens@kanban.local', 'Rubens Leite'),
+    ('kaiky@kanban.local', 'Kaiky Leandro'),
+    ('pedro@kanban.local', 'Pedro Santos'),
+    ('diego@kanban.local', 'Diego Sousa'),
+    ('cesar@kanban.local', 'César Bragança')
+) AS m (email, display_name) ON m.email = u.email
+ON CONFLICT (user_id) DO NOTHING;
@@ -0,0 +1,48 @@
+-- papeis e permissoes nomeadas (substituem o flag is_admin)
+CREATE TABLE IF NOT EXISTS roles (
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role       TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id     UUID NOT NULL,
+    role        TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    assigned_by UUID,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Administrador'),
+    ('supervisor', 'Supervisor'),
+    ('tecnico', 'Técnico'),
+    ('atendente', 'Atendente')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'ligacoes.manage'),
+    ('admin', 'agenda.manage'),
+    ('admin', 'avaliacoes.create'),
+    ('admin', 'avaliacoes.delete'),
+    ('admin', 'contatos.distribute'),
+    ('admin', 'users.manage'),
+    ('supervisor', 'ligacoes.manage'),
+    ('supervisor', 'agenda.manage'),
+    ('supervisor', 'avaliacoes.create'),
+    ('supervisor', 'avaliacoes.delete'),
+    ('supervisor', 'contatos.distribute'),
+    ('atendente', 'agenda.manage')
+ON CONFLICT DO NOTHING;
+
+-- quem ja era admin pelo flag ganha o papel
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'admin' FROM auth.users WHERE COALESCE((raw_user_meta_data->>'is_admin')::boolean, false)
+UNION
+SELECT id, 'admin' FROM local_users WHERE is_admin
+ON CONFLICT DO NOTHING;
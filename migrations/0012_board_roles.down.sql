@@ -0,0 +1,2 @@
+ALTER TABLE board_invitations DROP COLUMN IF EXISTS role;
+ALTER TABLE board_memberships DROP COLUMN IF EXISTS role;
@@ -0,0 +1,9 @@
+-- papel de cada membro no board; o dono continua em boards.owner_id
+ALTER TABLE board_memberships
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor'
+    CHECK (role IN ('editor', 'commenter', 'viewer'));
+
+-- papel escolhido no convite, aplicado quando o convite e aceito
+ALTER TABLE board_invitations
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor'
+    CHECK (role IN ('editor', 'commenter', 'viewer'));
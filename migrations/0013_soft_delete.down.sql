@@ -0,0 +1,14 @@
+-- o que estava na lixeira deixa de existir
+DELETE FROM cards WHERE deleted_at IS NOT NULL;
+DELETE FROM columns WHERE deleted_at IS NOT NULL;
+DELETE FROM boards WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS cards_deleted_at_idx;
+DROP INDEX IF EXISTS columns_deleted_at_idx;
+DROP INDEX IF EXISTS boards_deleted_at_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE cards DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE columns DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE columns DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE boards DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE boards DROP COLUMN IF EXISTS deleted_at;
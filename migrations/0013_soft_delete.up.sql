@@ -0,0 +1,11 @@
+-- lixeira: boards, colunas e cards excluidos ficam marcados ate a limpeza pela retencao
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS deleted_by UUID;
+
+CREATE INDEX IF NOT EXISTS boards_deleted_at_idx ON boards (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS columns_deleted_at_idx ON columns (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS cards_deleted_at_idx ON cards (deleted_at) WHERE deleted_at IS NOT NULL;
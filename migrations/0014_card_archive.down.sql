@@ -0,0 +1,10 @@
+-- cards arquivados voltam para o fim das colunas
+UPDATE cards c SET position = r.rn - 1
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY column_id ORDER BY archived_at NULLS FIRST, position, id) AS rn
+      FROM cards WHERE deleted_at IS NULL) r
+WHERE c.id = r.id;
+
+DROP INDEX IF EXISTS cards_completed_at_idx;
+DROP INDEX IF EXISTS cards_active_column_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS archived_by;
+ALTER TABLE cards DROP COLUMN IF EXISTS archived_at;
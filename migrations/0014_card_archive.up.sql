@@ -0,0 +1,6 @@
+-- cards arquivados saem da coluna mas continuam consultaveis; archived_by NULL = arquivamento automatico
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS archived_by UUID;
+
+CREATE INDEX IF NOT EXISTS cards_active_column_idx ON cards (column_id, position) WHERE archived_at IS NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS cards_completed_at_idx ON cards (completed_at) WHERE archived_at IS NULL AND completed_at IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE columns DROP COLUMN IF EXISTS require_due_date;
+ALTER TABLE columns DROP COLUMN IF EXISTS require_assignee;
+ALTER TABLE columns DROP COLUMN IF EXISTS wip_soft;
+ALTER TABLE columns DROP COLUMN IF EXISTS wip_limit;
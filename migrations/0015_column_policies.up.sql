@@ -0,0 +1,5 @@
+-- limite de WIP e regras de entrada por coluna; wip_soft = so avisa, nao bloqueia
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS wip_limit INTEGER CHECK (wip_limit > 0);
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS wip_soft BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS require_assignee BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS require_due_date BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,24 @@
+-- categoria da coluna: a conclusao do card passa a depender dela, e nao do titulo
+ALTER TABLE columns ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'in_progress'
+    CHECK (category IN ('backlog', 'in_progress', 'done_success', 'done_failure'));
+
+UPDATE columns SET category = 'backlog' WHERE lower(title) = 'a fazer';
+UPDATE columns SET category = 'done_success' WHERE lower(title) IN ('solucionado', 'concluído', 'concluido');
+UPDATE columns SET category = 'done_failure' WHERE lower(title) IN ('não solucionado', 'nao solucionado');
+
+-- quadros publicos ganhavam "Solucionado" e "Não Solucionado" na leitura das colunas; agora isso acontece uma vez, aqui
+INSERT INTO columns (board_id, title, position, color, category)
+SELECT b.id, 'Solucionado',
+       COALESCE((SELECT MAX(position) + 1 FROM columns c WHERE c.board_id = b.id AND c.deleted_at IS NULL), 0),
+       '#3fb950', 'done_success'
+FROM boards b
+WHERE b.is_public AND b.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM columns c WHERE c.board_id = b.id AND c.deleted_at IS NULL AND lower(c.title) = 'solucionado');
+
+INSERT INTO columns (board_id, title, position, color, category)
+SELECT b.id, 'Não Solucionado',
+       COALESCE((SELECT MAX(position) + 1 FROM columns c WHERE c.board_id = b.id AND c.deleted_at IS NULL), 0),
+       '#f85149', 'done_failure'
+FROM boards b
+WHERE b.is_public AND b.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM columns c WHERE c.board_id = b.id AND c.deleted_at IS NULL AND lower(c.title) = 'não solucionado');
@@ -0,0 +1,12 @@
+-- templates de board salvos pelos usuarios; os embutidos (kanban, atendimento) ficam no codigo
+CREATE TABLE IF NOT EXISTS board_templates (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id    UUID NOT NULL,
+    is_shared   BOOLEAN NOT NULL DEFAULT FALSE,
+    definition  JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS board_templates_owner_id_idx ON board_templates (owner_id);
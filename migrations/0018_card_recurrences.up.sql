@@ -0,0 +1,19 @@
+-- regra de recorrencia de um card: o card serve de modelo e cada ocorrencia vira um card novo na coluna alvo
+CREATE TABLE IF NOT EXISTS card_recurrences (
+    id               SERIAL PRIMARY KEY,
+    card_id          INTEGER NOT NULL UNIQUE REFERENCES cards (id) ON DELETE CASCADE,
+    target_column_id INTEGER NOT NULL REFERENCES columns (id) ON DELETE CASCADE,
+    frequency        TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'cron')),
+    weekdays         INTEGER[] NOT NULL DEFAULT '{}',
+    month_day        INTEGER CHECK (month_day BETWEEN 1 AND 31),
+    cron_expr        TEXT NOT NULL DEFAULT '',
+    time_of_day      TEXT NOT NULL DEFAULT '08:00',
+    due_in_days      INTEGER CHECK (due_in_days >= 0),
+    next_run_at      TIMESTAMPTZ NOT NULL,
+    last_run_at      TIMESTAMPTZ,
+    created_by       UUID NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_recurrences_next_run_at_idx ON card_recurrences (next_run_at);
+
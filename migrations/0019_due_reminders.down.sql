@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS cards_due_date_idx;
+DROP TABLE IF EXISTS card_due_reminders;
@@ -0,0 +1,11 @@
+-- lembretes de prazo ja enviados; a chave inclui o prazo, entao mudar a data libera um lembrete novo
+CREATE TABLE IF NOT EXISTS card_due_reminders (
+    card_id     INTEGER NOT NULL REFERENCES cards (id) ON DELETE CASCADE,
+    kind        TEXT NOT NULL CHECK (kind IN ('due_soon', 'overdue')),
+    due_date    TIMESTAMPTZ NOT NULL,
+    notified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (card_id, kind, due_date)
+);
+
+CREATE INDEX IF NOT EXISTS cards_due_date_idx ON cards (due_date)
+    WHERE due_date IS NOT NULL AND deleted_at IS NULL AND archived_at IS NULL;
@@ -0,0 +1,2 @@
+-- os papeis atribuidos continuam: nao da para separar os que vieram do flag
+SELECT 1;
@@ -0,0 +1,6 @@
+-- o flag is_admin deixa de conceder permissoes; quem o ganhou depois da 0011 recebe o papel admin
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'admin' FROM auth.users WHERE COALESCE((raw_user_meta_data->>'is_admin')::boolean, false)
+UNION
+SELECT id, 'admin' FROM local_users WHERE is_admin
+ON CONFLICT DO NOTHING;
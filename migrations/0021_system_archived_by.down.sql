@@ -0,0 +1,2 @@
+UPDATE cards SET archived_by = NULL
+WHERE archived_by = '00000000-0000-0000-0000-000000000000';
@@ -0,0 +1,3 @@
+-- o arquivamento automatico passa a gravar o ator de sistema (UUID nulo) em archived_by
+UPDATE cards SET archived_by = '00000000-0000-0000-0000-000000000000'
+WHERE archived_at IS NOT NULL AND archived_by IS NULL;
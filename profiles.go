@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// por quanto tempo um nome resolvido fica em cache
+const displayNameTTL = 5 * time.Minute
+
+// estrutura profile
+type Profile struct {
+	UserID      string    `json:"user_id"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	Phone       string    `json:"phone"`
+	Team        string    `json:"team"`
+	Active      bool      `json:"active"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// cache de nomes de exibicao por usuario
+type displayNameCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedDisplayName
+}
+
+type cachedDisplayName struct {
+	name    string
+	expires time.Time
+}
+
+// nome de exibicao: display_name do perfil, senao username, senao email
+func displayNameFor(user User, profile Profile) string {
+	if profile.DisplayName != "" {
+		return profile.DisplayName
+	}
+	if user.Username != "" {
+		return user.Username
+	}
+	return user.Email
+}
+
+// resolver de nomes usado em todo lugar que mostra quem fez algo
+func (app *App) getDisplayName(ctx context.Context, userID string) string {
+	app.names.mu.Lock()
+	entry, ok := app.names.entries[userID]
+	app.names.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.name
+	}
+
+	user, err := app.users.GetUser(ctx, userID)
+	if err != nil {
+		log.Printf("Aviso: não foi possível encontrar o nome para o userID %s: %v", userID, err)
+		return "Um usuário"
+	}
+	profile, err := app.profiles.GetProfile(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("Aviso: erro ao buscar perfil do userID %s: %v", userID, err)
+	}
+	name := displayNameFor(user, profile)
+	app.cacheDisplayName(userID, name)
+	return name
+}
+
+func (app *App) cacheDisplayName(userID, name string) {
+	app.names.mu.Lock()
+	defer app.names.mu.Unlock()
+	if app.names.entries == nil {
+		app.names.entries = make(map[string]cachedDisplayName)
+	}
+	app.names.entries[userID] = cachedDisplayName{name: name, expires: time.Now().Add(displayNameTTL)}
+}
+
+func (app *App) forgetDisplayName(userID string) {
+	app.names.mu.Lock()
+	defer app.names.mu.Unlock()
+	delete(app.names.entries, userID)
+}
+
+// completar usuarios com nome, avatar e status do perfil (uma consulta para a lista toda)
+func (app *App) withProfiles(ctx context.Context, users []User) []User {
+	profiles, err := app.profiles.ListProfiles(ctx)
+	if err != nil {
+		log.Printf("Aviso: erro ao buscar perfis: %v", err)
+	}
+	byUser := make(map[string]Profile, len(profiles))
+	for _, p := range profiles {
+		byUser[p.UserID] = p
+	}
+	for i := range users {
+		profile, ok := byUser[users[i].ID]
+		if !ok {
+			profile = Profile{Active: true}
+		}
+		users[i].DisplayName = displayNameFor(users[i], profile)
+		users[i].Active = profile.Active
+		if profile.AvatarURL != "" {
+			users[i].Avatar = profile.AvatarURL
+		}
+		app.cacheDisplayName(users[i].ID, users[i].DisplayName)
+	}
+	return users
+}
+
+// perfil salvo ou o padrao (ativo, sem nome proprio)
+func (app *App) profileFor(ctx context.Context, userID string) (Profile, error) {
+	profile, err := app.profiles.GetProfile(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return Profile{UserID: userID, Active: true}, nil
+	}
+	return profile, err
+}
+
+// estrutura profilepayload: campos ausentes ficam como estao
+type profilePayload struct {
+	DisplayName *string `json:"display_name"`
+	Phone       *string `json:"phone"`
+	Team        *string `json:"team"`
+	Active      *bool   `json:"active"`
+}
+
+// aplicar o payload; so admin pode mudar "active"
+func (p profilePayload) apply(profile *Profile, isAdmin bool) string {
+	fields := []struct {
+		value *string
+		dst   *string
+		max   int
+		name  string
+	}{
+		{p.DisplayName, &profile.DisplayName, 100, "display_name"},
+		{p.Phone, &profile.Phone, 30, "phone"},
+		{p.Team, &profile.Team, 60, "team"},
+	}
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.value)
+		if utf8.RuneCountInString(v) > f.max {
+			return "O campo " + f.name + " é muito longo"
+		}
+		*f.dst = v
+	}
+	if p.Active != nil {
+		if !isAdmin {
+			return "Apenas administradores podem alterar o status do perfil"
+		}
+		profile.Active = *p.Active
+	}
+	return ""
+}
+
+// resposta com o perfil e o nome ja resolvido
+func (app *App) profileResponse(ctx context.Context, profile Profile) fiber.Map {
+	return fiber.Map{
+		"user_id":       profile.UserID,
+		"display_name":  profile.DisplayName,
+		"resolved_name": app.getDisplayName(ctx, profile.UserID),
+		"avatar_url":    profile.AvatarURL,
+		"phone":         profile.Phone,
+		"team":          profile.Team,
+		"active":        profile.Active,
+		"updated_at":    profile.UpdatedAt,
+	}
+}
+
+func (app *App) updateProfile(c *fiber.Ctx, userID string, isAdmin bool) error {
+	var payload profilePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	ctx := context.Background()
+	profile, err := app.profileFor(ctx, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perfil"})
+	}
+	if msg := payload.apply(&profile, isAdmin); msg != "" {
+		status := fiber.StatusBadRequest
+		if payload.Active != nil && !isAdmin {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	profile, err = app.profiles.SaveProfile(ctx, profile)
+	if err != nil {
+		log.Printf("Erro ao salvar perfil de %s: %v", userID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar perfil"})
+	}
+	app.forgetDisplayName(userID)
+	return c.JSON(app.profileResponse(ctx, profile))
+}
+
+// endpoint meu perfil
+func (app *App) getMyProfile(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	profile, err := app.profileFor(context.Background(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perfil"})
+	}
+	return c.JSON(app.profileResponse(context.Background(), profile))
+}
+
+// endpoint editar meu perfil
+func (app *App) updateMyProfile(c *fiber.Ctx) error {
+	return app.updateProfile(c, c.Locals("userID").(string), false)
+}
+
+// usuario da rota /users/:id/profile, que precisa existir no provedor
+func (app *App) profileUserForRequest(c *fiber.Ctx) (string, bool, error) {
+	userID := c.Params("id")
+	if _, err := app.users.GetUser(context.Background(), userID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Usuário não encontrado"})
+		}
+		return "", false, c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar usuário"})
+	}
+	return userID, true, nil
+}
+
+// endpoint admin ver perfil
+func (app *App) adminGetProfile(c *fiber.Ctx) error {
+	userID, ok, resp := app.profileUserForRequest(c)
+	if !ok {
+		return resp
+	}
+	profile, err := app.profileFor(context.Background(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perfil"})
+	}
+	return c.JSON(app.profileResponse(context.Background(), profile))
+}
+
+// endpoint admin editar perfil
+func (app *App) adminUpdateProfile(c *fiber.Ctx) error {
+	userID, ok, resp := app.profileUserForRequest(c)
+	if !ok {
+		return resp
+	}
+	return app.updateProfile(c, userID, true)
+}
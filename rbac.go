@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permissoes nomeadas
+const (
+	PermLigacoesManage     = "ligacoes.manage"
+	PermAgendaManage       = "agenda.manage"
+	PermAvaliacoesCreate   = "avaliacoes.create"
+	PermAvaliacoesDelete   = "avaliacoes.delete"
+	PermContatosDistribute = "contatos.distribute"
+	PermUsersManage        = "users.manage"
+)
+
+var allPermissions = []string{
+	PermLigacoesManage,
+	PermAgendaManage,
+	PermAvaliacoesCreate,
+	PermAvaliacoesDelete,
+	PermContatosDistribute,
+	PermUsersManage,
+}
+
+// estrutura role
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// papeis padrao; os mesmos da migracao 0011_roles
+var defaultRoles = []Role{
+	{Name: "admin", Description: "Administrador", Permissions: allPermissions},
+	{Name: "supervisor", Description: "Supervisor", Permissions: []string{
+		PermLigacoesManage, PermAgendaManage, PermAvaliacoesCreate, PermAvaliacoesDelete, PermContatosDistribute,
+	}},
+	{Name: "tecnico", Description: "Técnico", Permissions: []string{}},
+	{Name: "atendente", Description: "Atendente", Permissions: []string{PermAgendaManage}},
+}
+
+// papeis e permissoes efetivas do usuario; o flag is_admin legado ja virou o papel admin nas migracoes
+func (app *App) userPermissions(ctx context.Context, userID string) ([]string, map[string]bool, error) {
+	roles, err := app.roles.UserRoles(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	perms := make(map[string]bool)
+	granted, err := app.roles.PermissionsForRoles(ctx, roles)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range granted {
+		perms[p] = true
+	}
+	return roles, perms, nil
+}
+
+func (app *App) hasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	_, perms, err := app.userPermissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return perms[permission], nil
+}
+
+// middleware que exige todas as permissoes listadas
+func (app *App) requirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+		if userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Usuário não autenticado"})
+		}
+		_, perms, err := app.userPermissions(context.Background(), userID)
+		if err != nil {
+			log.Printf("Erro ao verificar permissões do usuário %s: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar permissões de usuário"})
+		}
+		for _, p := range permissions {
+			if !perms[p] {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":      "Acesso negado. Esta ação requer a permissão " + p + ".",
+					"permission": p,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+func sortedPermissions(perms map[string]bool) []string {
+	list := make([]string, 0, len(perms))
+	for p := range perms {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// endpoint minhas permissoes
+func (app *App) getMyPermissions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roles, perms, err := app.userPermissions(context.Background(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar permissões"})
+	}
+	return c.JSON(fiber.Map{"roles": roles, "permissions": sortedPermissions(perms)})
+}
+
+// endpoint listar papeis
+func (app *App) getRoles(c *fiber.Ctx) error {
+	roles, err := app.roles.ListRoles(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar papéis"})
+	}
+	return c.JSON(roles)
+}
+
+// endpoint papeis de um usuario
+func (app *App) getUserRoles(c *fiber.Ctx) error {
+	userID, ok, resp := app.profileUserForRequest(c)
+	if !ok {
+		return resp
+	}
+	roles, perms, err := app.userPermissions(context.Background(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar papéis"})
+	}
+	return c.JSON(fiber.Map{"user_id": userID, "roles": roles, "permissions": sortedPermissions(perms)})
+}
+
+// endpoint definir papeis de um usuario (substitui o conjunto)
+func (app *App) setUserRoles(c *fiber.Ctx) error {
+	targetID, ok, resp := app.profileUserForRequest(c)
+	if !ok {
+		return resp
+	}
+	adminID := c.Locals("userID").(string)
+	var payload struct {
+		Roles []string `json:"roles"`
+	}
+	if err := c.BodyParser(&payload); err != nil || payload.Roles == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido: informe roles"})
+	}
+
+	ctx := context.Background()
+	known, err := app.roles.ListRoles(ctx)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar papéis"})
+	}
+	valid := make(map[string]bool, len(known))
+	for _, r := range known {
+		valid[r.Name] = true
+	}
+	roles := make([]string, 0, len(payload.Roles))
+	seen := make(map[string]bool)
+	for _, r := range payload.Roles {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if !valid[r] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Papel desconhecido: " + r})
+		}
+		if !seen[r] {
+			seen[r] = true
+			roles = append(roles, r)
+		}
+	}
+
+	// ninguem tira de si mesmo a permissao de gerenciar usuarios
+	if targetID == adminID {
+		granted, err := app.roles.PermissionsForRoles(ctx, roles)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar papéis"})
+		}
+		keeps := false
+		for _, p := range granted {
+			keeps = keeps || p == PermUsersManage
+		}
+		if !keeps {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Você não pode remover a própria permissão de gerenciar usuários"})
+		}
+	}
+
+	if err := app.roles.SetUserRoles(ctx, targetID, roles, adminID); err != nil {
+		log.Printf("Erro ao definir papéis de %s: %v", targetID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar papéis"})
+	}
+	return app.getUserRoles(c)
+}
@@ -17,7 +17,7 @@ interface CardProps {
 
 export function KanbanCard({ card, isOverlay = false }: CardProps) {
   const { openModal } = useModal();
-  const { board, users, removeCard, updateCard, solucionadoId, naoSolucionadoId, isColumnDragging } = useBoard();
+  const { board, columns, users, removeCard, fetchBoardData, solucionadoId, naoSolucionadoId, isColumnDragging } = useBoard();
 
   const {
     attributes,
@@ -135,33 +135,23 @@ export function KanbanCard({ card, isOverlay = false }: CardProps) {
     if (!board) return;
     if (board.is_public && isCompleted) return;
 
-    let updatedCardData: CardType;
+    // a conclusao segue a categoria da coluna: concluir e mover para a coluna "done", reabrir e voltar para a primeira de trabalho
+    let targetColumnId: number | null | undefined = solucionadoId;
     let successMessage = "Tarefa concluída!";
-
-    if (board.is_public) {
-      if (!solucionadoId) {
-        toast.error("Coluna 'Solucionado' não configurada.");
-        return;
-      }
-      updatedCardData = { 
-        ...card, 
-        column_id: solucionadoId,
-        completed_at: new Date().toISOString()
-      };
-    } else {
-      const isCurrentlyCompleted = !!card.completed_at;
-      updatedCardData = { 
-        ...card, 
-        completed_at: isCurrentlyCompleted ? null : new Date().toISOString() 
-      };
-      if (isCurrentlyCompleted) {
-        successMessage = "Tarefa reaberta!";
-      }
+    if (isCompleted) {
+      targetColumnId = [...columns]
+        .sort((a, b) => a.position - b.position)
+        .find(col => col.category !== 'done_success' && col.category !== 'done_failure')?.id;
+      successMessage = "Tarefa reaberta!";
+    }
+    if (!targetColumnId) {
+      toast.error(isCompleted ? "Nenhuma coluna de trabalho encontrada." : "Coluna de conclusão não configurada.");
+      return;
     }
 
     try {
-      await cardService.updateCard(card.id, updatedCardData);
-      updateCard(updatedCardData);
+      await cardService.moveCard(card.id, targetColumnId, 0);
+      await fetchBoardData(board.id, !board.is_public);
       toast.success(successMessage);
     } catch (error) {
       toast.error("Não foi possível atualizar a tarefa.");
@@ -57,10 +57,12 @@ export function BoardProvider({ children }: { children: ReactNode }) {
       ]);
       
       setBoardMembers(members || []);
+      // colunas de status pela categoria, nao pelo titulo
+      const byPosition = [...fetchedColumns].sort((a, b) => a.position - b.position);
+      setSolucionadoId(byPosition.find(col => col.category === 'done_success')?.id ?? null);
+      setNaoSolucionadoId(byPosition.find(col => col.category === 'done_failure')?.id ?? null);
       const columnsWithCards = await Promise.all(
         fetchedColumns.map(async (col) => {
-          if(col.title.toLowerCase() === 'solucionado') setSolucionadoId(col.id);
-          if(col.title.toLowerCase() === 'não solucionado') setNaoSolucionadoId(col.id);
           const cards = await cardService.getCardsForColumn(col.id);
           return { ...col, cards: cards || [] };
         })
@@ -107,11 +109,21 @@ export function BoardProvider({ children }: { children: ReactNode }) {
         setColumns(prev => prev.map(col => col.id === newCard.column_id ? { ...col, cards: [...col.cards, newCard] } : col));
         break;
       }
-      case 'CARD_DELETED': {
+      case 'CARD_DELETED':
+      case 'CARD_ARCHIVED': {
         const { card_id } = message.payload;
         setColumns(prev => prev.map(col => ({...col, cards: col.cards.filter(c => c.id !== card_id)})));
         break;
       }
+      case 'CARD_UNARCHIVED': {
+        // o card volta para o fim da coluna
+        const restoredCard = message.payload as Card;
+        setColumns(prev => prev.map(col => {
+          const cards = col.cards.filter(c => c.id !== restoredCard.id);
+          return col.id === restoredCard.column_id ? { ...col, cards: [...cards, restoredCard] } : { ...col, cards };
+        }));
+        break;
+      }
       
        case 'CARD_MOVED': {
         const { card, old_column_id } = message.payload as { card: Card, old_column_id: number };
@@ -161,6 +173,7 @@ export function BoardProvider({ children }: { children: ReactNode }) {
       }
 
       case 'BOARD_STATE_UPDATED':
+      case 'RESYNC_REQUIRED':
       case 'COLUMN_CREATED':
       case 'COLUMN_UPDATED':
       case 'COLUMN_DELETED':
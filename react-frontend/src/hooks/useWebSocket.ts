@@ -1,8 +1,13 @@
 import { useEffect, useRef } from 'react';
 import { useAuth } from '../contexts/AuthContext';
+import { supabaseClient } from '../api/supabaseClient';
+
+// tempo esperando um seq atrasado (outra replica) antes de pedir os eventos perdidos
+const GAP_TIMEOUT_MS = 1000;
 
 export function useWebSocket(boardId: number | undefined, onMessage: (message: any) => void) {
   const ws = useRef<WebSocket | null>(null);
+  const lastSeq = useRef<number | null>(null);
   const { user } = useAuth();
 
   const onMessageRef = useRef(onMessage);
@@ -24,29 +29,115 @@ export function useWebSocket(boardId: number | undefined, onMessage: (message: a
       ? `${protocol}://${host}/ws/board/${boardId}`
       : `${protocol}://${window.location.hostname}:10000/ws/board/${boardId}`;
     
-    ws.current = new WebSocket(wsUrl);
+    let cancelled = false;
+    let reconnectTimer: ReturnType<typeof setTimeout> | undefined;
+    let gapTimer: ReturnType<typeof setTimeout> | undefined;
+    let resumeNow = false;
+    // o servidor fechou de proposito (acesso removido ou board excluido)
+    let closedByServer = false;
+    // mensagens que chegaram antes de um seq anterior
+    const pending = new Map<number, any>();
+    lastSeq.current = null;
 
-    ws.current.onopen = () => console.log(`[WebSocket] Conectado ao board ${boardId}`);
-    
-    ws.current.onmessage = (event) => {
-      try {
-        const messageData = JSON.parse(event.data);
-        
-        if (messageData.sender_id && messageData.sender_id === user.id) {
-          return;
-        }
+    const clearGap = () => {
+      clearTimeout(gapTimer);
+      gapTimer = undefined;
+      pending.clear();
+    };
 
-        onMessageRef.current(messageData);
+    const deliver = (messageData: any) => {
+      if (messageData.sender_id && messageData.sender_id === user.id) {
+        return;
+      }
+      onMessageRef.current(messageData);
+    };
+
+    // o buraco nao fechou: reconecta com ?since= e o servidor reenvia o que faltou (ou pede resync)
+    const resumeFromGap = () => {
+      gapTimer = undefined;
+      pending.clear();
+      resumeNow = true;
+      ws.current?.close();
+    };
 
-      } catch (error) {
-        console.error("[WebSocket] Erro ao processar mensagem:", error);
+    const connect = async () => {
+      const { data: { session } } = await supabaseClient.auth.getSession();
+      if (cancelled || !session?.access_token) {
+        return;
       }
+
+      const url = lastSeq.current !== null ? `${wsUrl}?since=${lastSeq.current}` : wsUrl;
+      ws.current = new WebSocket(url, ['bearer', session.access_token]);
+
+      ws.current.onopen = () => console.log(`[WebSocket] Conectado ao board ${boardId}`);
+
+      ws.current.onmessage = (event) => {
+        try {
+          const messageData = JSON.parse(event.data);
+
+          if (messageData.type === 'ACCESS_REVOKED' || messageData.type === 'BOARD_CLOSED') {
+            closedByServer = true;
+            onMessageRef.current(messageData);
+            return;
+          }
+
+          if (messageData.type === 'CONNECTED' || messageData.type === 'RESYNC_REQUIRED') {
+            lastSeq.current = messageData.payload?.last_seq ?? 0;
+            clearGap();
+            if (messageData.type === 'CONNECTED') {
+              return;
+            }
+          } else if (typeof messageData.seq === 'number') {
+            if (lastSeq.current !== null && messageData.seq <= lastSeq.current) {
+              return;
+            }
+            if (lastSeq.current !== null && messageData.seq > lastSeq.current + 1) {
+              pending.set(messageData.seq, messageData);
+              if (!gapTimer) {
+                gapTimer = setTimeout(resumeFromGap, GAP_TIMEOUT_MS);
+              }
+              return;
+            }
+            lastSeq.current = messageData.seq;
+            deliver(messageData);
+            // entrega o que estava esperando este seq
+            while (pending.has(lastSeq.current + 1)) {
+              const next = pending.get(lastSeq.current + 1);
+              pending.delete(lastSeq.current + 1);
+              lastSeq.current += 1;
+              deliver(next);
+            }
+            if (pending.size === 0) {
+              clearTimeout(gapTimer);
+              gapTimer = undefined;
+            }
+            return;
+          }
+
+          deliver(messageData);
+
+        } catch (error) {
+          console.error("[WebSocket] Erro ao processar mensagem:", error);
+        }
+      };
+
+      ws.current.onerror = (error) => console.error("[WebSocket] Erro:", error);
+      ws.current.onclose = () => {
+        console.log(`[WebSocket] Desconectado do board ${boardId}`);
+        clearGap();
+        if (!cancelled && !closedByServer) {
+          reconnectTimer = setTimeout(connect, resumeNow ? 0 : 2000);
+          resumeNow = false;
+        }
+      };
     };
 
-    ws.current.onerror = (error) => console.error("[WebSocket] Erro:", error);
-    ws.current.onclose = () => console.log(`[WebSocket] Desconectado do board ${boardId}`);
+    connect();
 
     return () => {
+      cancelled = true;
+      clearTimeout(reconnectTimer);
+      clearGap();
       if (ws.current?.readyState === WebSocket.OPEN) {
         ws.current?.close();
       }
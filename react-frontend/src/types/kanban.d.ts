@@ -27,6 +27,7 @@ export interface Column {
   title: string;
   position: number;
   color: string;
+  category: 'backlog' | 'in_progress' | 'done_success' | 'done_failure';
   cards: Card[];
 }
 
@@ -53,7 +54,7 @@ export interface Comment {
 export interface Notification {
     id: number;
     user_id: string;
-    type: 'board_invitation' | 'new_task_assigned' | 'invitation_accepted';
+    type: 'board_invitation' | 'new_task_assigned' | 'invitation_accepted' | 'mention';
     message: string;
     is_read: boolean;
     related_board_id?: number;
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// prefixo dos canais NOTIFY, um por board
+const boardChannelPrefix = "board_events_"
+
+// limite do payload do NOTIFY no Postgres (8000 bytes) com folga
+const maxNotifyPayload = 7900
+
+// quantos IDs de mensagem lembrar para descartar duplicadas
+const seenMessagesCapacity = 2048
+
+// controles repassados entre instancias; nao chegam aos clientes
+const (
+	controlDisconnectUser = "disconnect_user"
+	controlCloseBoard     = "close_board"
+)
+
+// estrutura relaycontrol
+type relayControl struct {
+	ID      string `json:"id"`
+	Control string `json:"control"`
+	UserID  string `json:"user_id,omitempty"`
+}
+
+// relay distribui as mensagens do hub entre instancias
+type relay interface {
+	publish(boardID int, messageID string, payload []byte)
+	subscribe(boardID int)
+	unsubscribe(boardID int)
+}
+
+// gerar id de mensagem
+func newMessageID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+func boardChannel(boardID int) string {
+	return boardChannelPrefix + strconv.Itoa(boardID)
+}
+
+// estrutura seenset, janela fixa de IDs recentes
+type seenSet struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newSeenSet(capacity int) *seenSet {
+	return &seenSet{ids: make(map[string]struct{}, capacity), order: make([]string, capacity)}
+}
+
+// marca o ID e informa se ja tinha sido visto
+func (s *seenSet) check(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[id]; ok {
+		return true
+	}
+	if old := s.order[s.next]; old != "" {
+		delete(s.ids, old)
+	}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.ids[id] = struct{}{}
+	return false
+}
+
+// estrutura pgrelay, fan-out via LISTEN/NOTIFY
+type pgRelay struct {
+	db      *pgxpool.Pool
+	hub     *Hub
+	seen    *seenSet
+	mu      sync.Mutex
+	boards  map[int]struct{}
+	changed chan struct{}
+}
+
+func newPgRelay(db *pgxpool.Pool, hub *Hub) *pgRelay {
+	return &pgRelay{
+		db:      db,
+		hub:     hub,
+		seen:    newSeenSet(seenMessagesCapacity),
+		boards:  make(map[int]struct{}),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+func (r *pgRelay) notifyChanged() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (r *pgRelay) subscribe(boardID int) {
+	r.mu.Lock()
+	r.boards[boardID] = struct{}{}
+	r.mu.Unlock()
+	r.notifyChanged()
+}
+
+func (r *pgRelay) unsubscribe(boardID int) {
+	r.mu.Lock()
+	delete(r.boards, boardID)
+	r.mu.Unlock()
+	r.notifyChanged()
+}
+
+// payload grande demais para o NOTIFY: as outras instancias recebem
+// apenas um aviso para os clientes recarregarem o board, com o mesmo seq
+// para o cliente nao enxergar um buraco
+func notifyPayload(messageID string, payload []byte) []byte {
+	if len(payload) <= maxNotifyPayload {
+		return payload
+	}
+	var envelope struct {
+		Seq int64 `json:"seq"`
+	}
+	json.Unmarshal(payload, &envelope)
+	fallback, _ := json.Marshal(WsMessage{ID: messageID, Seq: envelope.Seq, Type: "BOARD_STATE_UPDATED"})
+	return fallback
+}
+
+// publicar no canal do board; a propria instancia ja entregou localmente
+func (r *pgRelay) publish(boardID int, messageID string, payload []byte) {
+	r.seen.check(messageID)
+	payload = notifyPayload(messageID, payload)
+	_, err := r.db.Exec(context.Background(), "SELECT pg_notify($1, $2)", boardChannel(boardID), string(payload))
+	if err != nil {
+		log.Printf("Erro ao publicar mensagem no canal do board %d: %v", boardID, err)
+	}
+}
+
+// loop principal, reconecta com backoff
+func (r *pgRelay) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := r.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Conexão LISTEN do realtime caiu: %v. Reconectando em %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (r *pgRelay) listen(ctx context.Context) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// conexao dedicada: sai do pool para o LISTEN nao vazar para outras queries
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	listening := make(map[int]struct{})
+	for {
+		if err := r.syncChannels(ctx, conn, listening); err != nil {
+			return err
+		}
+
+		waitCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-r.changed:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, context.Canceled) {
+				continue
+			}
+			return err
+		}
+		r.deliver(notification.Channel, notification.Payload)
+	}
+}
+
+// aplicar LISTEN/UNLISTEN conforme os boards com conexoes locais
+func (r *pgRelay) syncChannels(ctx context.Context, conn *pgx.Conn, listening map[int]struct{}) error {
+	r.mu.Lock()
+	wanted := make(map[int]struct{}, len(r.boards))
+	for boardID := range r.boards {
+		wanted[boardID] = struct{}{}
+	}
+	r.mu.Unlock()
+
+	for boardID := range wanted {
+		if _, ok := listening[boardID]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{boardChannel(boardID)}.Sanitize()); err != nil {
+			return fmt.Errorf("LISTEN no board %d: %w", boardID, err)
+		}
+		listening[boardID] = struct{}{}
+	}
+	for boardID := range listening {
+		if _, ok := wanted[boardID]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{boardChannel(boardID)}.Sanitize()); err != nil {
+			return fmt.Errorf("UNLISTEN no board %d: %w", boardID, err)
+		}
+		delete(listening, boardID)
+	}
+	return nil
+}
+
+// repassar notificacao recebida para as conexoes locais
+func (r *pgRelay) deliver(channel, payload string) {
+	boardID, err := strconv.Atoi(strings.TrimPrefix(channel, boardChannelPrefix))
+	if err != nil {
+		return
+	}
+	var envelope relayControl
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		log.Printf("Aviso: notificação inválida no canal %s: %v", channel, err)
+		return
+	}
+	if envelope.ID != "" && r.seen.check(envelope.ID) {
+		return
+	}
+	if envelope.Control != "" {
+		r.hub.applyControl(boardID, envelope)
+		return
+	}
+	r.hub.broadcast(boardID, []byte(payload))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSeenSetDedupAndWindow(t *testing.T) {
+	s := newSeenSet(3)
+	if s.check("a") || !s.check("a") {
+		t.Fatal("o segundo check do mesmo ID deveria acusar duplicada")
+	}
+	s.check("b")
+	s.check("c")
+	// janela cheia: "d" empurra "a" para fora
+	s.check("d")
+	if s.check("a") {
+		t.Fatal("IDs fora da janela deveriam ser esquecidos")
+	}
+	if !s.check("d") {
+		t.Fatal("IDs recentes continuam lembrados")
+	}
+}
+
+func TestNotifyPayloadFallback(t *testing.T) {
+	small := encodeWsMessage(WsMessage{ID: "m1", Seq: 7, Type: "CARD_UPDATED", Payload: map[string]string{"title": "x"}})
+	if got := notifyPayload("m1", small); string(got) != string(small) {
+		t.Fatalf("payload pequeno deveria seguir intacto: %s", got)
+	}
+
+	big := encodeWsMessage(WsMessage{ID: "m2", Seq: 8, Type: "CARD_UPDATED", Payload: map[string]string{"description": strings.Repeat("a", maxNotifyPayload)}})
+	got := notifyPayload("m2", big)
+	if len(got) > maxNotifyPayload {
+		t.Fatalf("fallback ainda grande demais: %d bytes", len(got))
+	}
+	var message WsMessage
+	if err := json.Unmarshal(got, &message); err != nil || message.Type != "BOARD_STATE_UPDATED" || message.ID != "m2" || message.Seq != 8 {
+		t.Fatalf("fallback inesperado: %s (%v)", got, err)
+	}
+}
+
+func TestRelayDeliverDedupAndControls(t *testing.T) {
+	h := newHub()
+	r := newPgRelay(nil, h)
+	h.relay = r
+	ana, bia := testClient(5, "ana"), testClient(5, "bia")
+	h.register(ana)
+	h.register(bia)
+	if _, ok := r.boards[5]; !ok {
+		t.Fatal("board com conexoes deveria entrar na lista de LISTEN")
+	}
+	channel := boardChannel(5)
+
+	// mensagem publicada por esta instancia volta pelo NOTIFY e e descartada
+	r.seen.check("local")
+	r.deliver(channel, `{"id":"local","type":"CARD_UPDATED"}`)
+	r.deliver(channel, `{"id":"remota","type":"CARD_UPDATED"}`)
+	r.deliver(channel, `{"id":"remota","type":"CARD_UPDATED"}`)
+	r.deliver("canal_estranho", `{"id":"x","type":"CARD_UPDATED"}`)
+	r.deliver(channel, `nao e json`)
+	if len(ana.send) != 1 || string(<-ana.send) != `{"id":"remota","type":"CARD_UPDATED"}` {
+		t.Fatal("so a mensagem remota deveria chegar, uma unica vez")
+	}
+	<-bia.send
+
+	// controles agem no hub local e nao chegam como mensagem comum
+	r.deliver(channel, `{"id":"c1","control":"disconnect_user","user_id":"ana"}`)
+	if got := drained(t, ana); len(got) != 1 || !strings.Contains(got[0], "ACCESS_REVOKED") {
+		t.Fatalf("ana deveria ser desconectada: %v", got)
+	}
+	if len(bia.send) != 0 {
+		t.Fatal("controle nao deveria ser repassado aos clientes")
+	}
+	r.deliver(channel, `{"id":"c2","control":"close_board"}`)
+	if got := drained(t, bia); len(got) != 1 || !strings.Contains(got[0], "BOARD_CLOSED") {
+		t.Fatalf("board deveria fechar: %v", got)
+	}
+	if h.clientCount(5) != 0 {
+		t.Fatalf("nenhuma conexao deveria sobrar: %d", h.clientCount(5))
+	}
+	if _, ok := r.boards[5]; ok {
+		t.Fatal("board sem conexoes deveria sair da lista de LISTEN")
+	}
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// frequencias de recorrencia
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+	RecurrenceCron    = "cron"
+)
+
+// estrutura cardrecurrence; o card e o modelo, cada ocorrencia vira um card novo em ColumnID.
+// Horarios no fuso do servidor
+type CardRecurrence struct {
+	ID        int        `json:"id"`
+	CardID    int        `json:"card_id"`
+	ColumnID  int        `json:"column_id"`
+	Frequency string     `json:"frequency"`
+	Weekdays  []int      `json:"weekdays"`
+	MonthDay  *int       `json:"month_day,omitempty"`
+	CronExpr  string     `json:"cron,omitempty"`
+	TimeOfDay string     `json:"time"`
+	DueInDays *int       `json:"due_in_days"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+const recurrenceSelectColumns = `id, card_id, target_column_id, frequency, weekdays, month_day, cron_expr, time_of_day,
+	due_in_days, next_run_at, last_run_at, created_by, created_at`
+
+func scanRecurrence(row pgx.Row, r *CardRecurrence) error {
+	return row.Scan(&r.ID, &r.CardID, &r.ColumnID, &r.Frequency, &r.Weekdays, &r.MonthDay, &r.CronExpr, &r.TimeOfDay,
+		&r.DueInDays, &r.NextRunAt, &r.LastRunAt, &r.CreatedBy, &r.CreatedAt)
+}
+
+// validar a regra; devolve a mensagem para o 400
+func (r *CardRecurrence) validate() string {
+	if r.TimeOfDay == "" {
+		r.TimeOfDay = "08:00"
+	}
+	if r.Weekdays == nil {
+		r.Weekdays = []int{}
+	}
+	if r.DueInDays != nil && *r.DueInDays < 0 {
+		return "due_in_days não pode ser negativo"
+	}
+	switch r.Frequency {
+	case RecurrenceDaily:
+	case RecurrenceWeekly:
+		if len(r.Weekdays) == 0 {
+			return "Informe weekdays (0 = domingo ... 6 = sábado)"
+		}
+		for _, d := range r.Weekdays {
+			if d < 0 || d > 6 {
+				return "weekdays deve ter valores de 0 (domingo) a 6 (sábado)"
+			}
+		}
+	case RecurrenceMonthly:
+		if r.MonthDay == nil || *r.MonthDay < 1 || *r.MonthDay > 31 {
+			return "Informe month_day entre 1 e 31"
+		}
+	case RecurrenceCron:
+		if _, err := parseCron(r.CronExpr); err != nil {
+			return "Expressão cron inválida: " + err.Error()
+		}
+		return ""
+	default:
+		return "frequency deve ser daily, weekly, monthly ou cron"
+	}
+	if _, _, err := parseTimeOfDay(r.TimeOfDay); err != nil {
+		return "time deve estar no formato HH:MM"
+	}
+	return ""
+}
+
+func parseTimeOfDay(raw string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// proxima ocorrencia estritamente depois de after
+func (r *CardRecurrence) next(after time.Time) (time.Time, error) {
+	if r.Frequency == RecurrenceCron {
+		sched, err := parseCron(r.CronExpr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return sched.next(after)
+	}
+	hour, minute, err := parseTimeOfDay(r.TimeOfDay)
+	if err != nil {
+		return time.Time{}, err
+	}
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, after.Location())
+	}
+	y, m, d := after.Date()
+	switch r.Frequency {
+	case RecurrenceDaily:
+		for i := 0; i <= 1; i++ {
+			if t := at(y, m, d+i); t.After(after) {
+				return t, nil
+			}
+		}
+	case RecurrenceWeekly:
+		for i := 0; i <= 7; i++ {
+			t := at(y, m, d+i)
+			if !t.After(after) {
+				continue
+			}
+			for _, wd := range r.Weekdays {
+				if time.Weekday(wd) == t.Weekday() {
+					return t, nil
+				}
+			}
+		}
+	case RecurrenceMonthly:
+		// meses mais curtos caem no ultimo dia
+		for i := 0; i <= 12; i++ {
+			first := time.Date(y, m+time.Month(i), 1, 0, 0, 0, 0, after.Location())
+			lastDay := first.AddDate(0, 1, -1).Day()
+			day := *r.MonthDay
+			if day > lastDay {
+				day = lastDay
+			}
+			if t := at(first.Year(), first.Month(), day); t.After(after) {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("recorrência %q sem próxima ocorrência", r.Frequency)
+}
+
+// prazo do card gerado: due_in_days depois da ocorrencia ou, sem ele, a ocorrencia seguinte
+func (r *CardRecurrence) dueDate(runAt time.Time) *time.Time {
+	if r.DueInDays != nil {
+		due := runAt.AddDate(0, 0, *r.DueInDays)
+		return &due
+	}
+	due, err := r.next(runAt)
+	if err != nil {
+		return nil
+	}
+	return &due
+}
+
+// cron de 5 campos (minuto hora dia mes dia-da-semana) com *, listas, intervalos e passos
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("use 5 campos: minuto hora dia mês dia-da-semana")
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 tambem e domingo
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	return &s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("passo inválido em %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil || a > b {
+				return 0, fmt.Errorf("intervalo inválido %q", rangePart)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("valor inválido %q", rangePart)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < min || hi > max {
+			return 0, fmt.Errorf("%q fora de %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	// com dia e dia-da-semana restritos, vale qualquer um dos dois (como no cron)
+	if !s.domStar && !s.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (s *cronSchedule) next(after time.Time) (time.Time, error) {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("a expressão cron não ocorre nos próximos 5 anos")
+}
+
+// endpoint ver recorrencia do card
+func (app *App) getCardRecurrence(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapView)
+	if !ok {
+		return resp
+	}
+	var r CardRecurrence
+	err := scanRecurrence(app.db.QueryRow(context.Background(),
+		"SELECT "+recurrenceSelectColumns+" FROM card_recurrences WHERE card_id = $1", cardID), &r)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "O card não tem recorrência"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar a recorrência"})
+	}
+	return c.JSON(r)
+}
+
+// endpoint criar/substituir recorrencia do card
+func (app *App) setCardRecurrence(c *fiber.Ctx) error {
+	cardID, boardID, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+
+	var r CardRecurrence
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+	}
+	if msg := r.validate(); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	// sem column_id as ocorrencias caem na coluna atual do modelo
+	if r.ColumnID == 0 {
+		card, err := app.getCardByID(cardID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o card"})
+		}
+		r.ColumnID = card.ColumnID
+	}
+	targetBoardID, err := app.getBoardIDFromColumn(r.ColumnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna de destino não encontrada"})
+	}
+	if targetBoardID != boardID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A coluna de destino precisa ser do mesmo quadro do card"})
+	}
+	nextRun, err := r.next(time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err = scanRecurrence(app.db.QueryRow(context.Background(), `
+		INSERT INTO card_recurrences (card_id, target_column_id, frequency, weekdays, month_day, cron_expr, time_of_day, due_in_days, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (card_id) DO UPDATE SET
+			target_column_id = EXCLUDED.target_column_id, frequency = EXCLUDED.frequency, weekdays = EXCLUDED.weekdays,
+			month_day = EXCLUDED.month_day, cron_expr = EXCLUDED.cron_expr, time_of_day = EXCLUDED.time_of_day,
+			due_in_days = EXCLUDED.due_in_days, next_run_at = EXCLUDED.next_run_at, created_by = EXCLUDED.created_by
+		RETURNING `+recurrenceSelectColumns,
+		cardID, r.ColumnID, r.Frequency, r.Weekdays, r.MonthDay, r.CronExpr, r.TimeOfDay, r.DueInDays, nextRun, userID), &r)
+	if err != nil {
+		log.Printf("Erro ao salvar a recorrência do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar a recorrência"})
+	}
+	return c.JSON(r)
+}
+
+// endpoint remover recorrencia do card
+func (app *App) deleteCardRecurrence(c *fiber.Ctx) error {
+	cardID, _, ok, resp := app.cardBoardForRequest(c, CapEdit)
+	if !ok {
+		return resp
+	}
+	tag, err := app.db.Exec(context.Background(), "DELETE FROM card_recurrences WHERE card_id = $1", cardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover a recorrência"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "O card não tem recorrência"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// gerar as ocorrencias vencidas ate now; devolve quantos cards foram criados
+func (app *App) spawnDueRecurrences(ctx context.Context, now time.Time) (int, error) {
+	rows, err := app.db.Query(ctx, "SELECT id FROM card_recurrences WHERE next_run_at <= $1 ORDER BY next_run_at", now)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	spawned := 0
+	for _, id := range ids {
+		ok, err := app.spawnRecurrence(ctx, id, now)
+		if err != nil {
+			log.Printf("Erro ao gerar a ocorrência da recorrência %d: %v", id, err)
+			continue
+		}
+		if ok {
+			spawned++
+		}
+	}
+	return spawned, nil
+}
+
+// uma ocorrencia: clona o card modelo na coluna alvo e agenda a proxima. Ocorrencias perdidas
+// (servidor parado) geram um card so
+func (app *App) spawnRecurrence(ctx context.Context, recurrenceID int, now time.Time) (bool, error) {
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var r CardRecurrence
+	err = scanRecurrence(tx.QueryRow(ctx, "SELECT "+recurrenceSelectColumns+`
+		FROM card_recurrences WHERE id = $1 AND next_run_at <= $2 FOR UPDATE SKIP LOCKED`, recurrenceID, now), &r)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	runAt := r.NextRunAt
+	nextRun, err := r.next(now)
+	if err != nil {
+		return false, err
+	}
+	advance := func() error {
+		_, err := tx.Exec(ctx, "UPDATE card_recurrences SET next_run_at = $1, last_run_at = $2 WHERE id = $3", nextRun, now, r.ID)
+		return err
+	}
+	skip := func(reason string) (bool, error) {
+		log.Printf("Recorrência %d (card %d) pulada: %s", r.ID, r.CardID, reason)
+		if err := advance(); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	// modelo na lixeira ou coluna/board excluidos: pula a ocorrencia
+	card := Card{ColumnID: r.ColumnID}
+	err = tx.QueryRow(ctx, `
+		SELECT title, COALESCE(description, ''), COALESCE(assigned_to, ''), COALESCE(priority, 'media')
+		FROM cards WHERE id = $1 AND deleted_at IS NULL`, r.CardID).Scan(
+		&card.Title, &card.Description, &card.AssignedTo, &card.Priority)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return skip("card modelo na lixeira")
+	}
+	if err != nil {
+		return false, err
+	}
+	var boardID int
+	var category ColumnCategory
+	err = tx.QueryRow(ctx, `
+		SELECT col.board_id, col.category FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.id = $1 AND col.deleted_at IS NULL AND b.deleted_at IS NULL`, r.ColumnID).Scan(&boardID, &category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return skip("coluna de destino excluída")
+	}
+	if err != nil {
+		return false, err
+	}
+	card.DueDate = r.dueDate(runAt)
+
+	violation, wipExceeded, err := checkColumnEntry(ctx, tx, r.ColumnID, card)
+	if err != nil {
+		return false, err
+	}
+	if violation != nil {
+		return skip(violation.Message)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position, completed_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6,
+			(SELECT COUNT(*) FROM cards WHERE column_id = $1 AND deleted_at IS NULL AND archived_at IS NULL),
+			CASE WHEN $7 THEN NOW() END)
+		RETURNING id`,
+		r.ColumnID, card.Title, card.Description, card.AssignedTo, card.Priority, card.DueDate, category.done()).Scan(&card.ID)
+	if err != nil {
+		return false, err
+	}
+	// etiquetas e checklist (desmarcado) do modelo
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO card_labels (card_id, label_id) SELECT $1, label_id FROM card_labels WHERE card_id = $2", card.ID, r.CardID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO card_checklist_items (card_id, text, position)
+		SELECT $1, text, position FROM card_checklist_items WHERE card_id = $2`, card.ID, r.CardID); err != nil {
+		return false, err
+	}
+	if err := app.recordCardActivity(tx, CardActivity{CardID: card.ID, BoardID: boardID, ActorID: r.CreatedBy, Action: "created", NewValue: activityValue(card.Title)}); err != nil {
+		return false, err
+	}
+	if card.AssignedTo != "" {
+		if assigneeID, err := app.getUserIDByUsername(card.AssignedTo); err == nil {
+			err = app.createNotification(tx, Notification{
+				UserID:         assigneeID,
+				Type:           "new_task_assigned",
+				Message:        fmt.Sprintf("Nova ocorrência da tarefa recorrente: %s", card.Title),
+				RelatedBoardID: &boardID,
+				RelatedCardID:  &card.ID,
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := advance(); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	created, err := app.getCardByID(card.ID)
+	if err != nil {
+		log.Printf("Erro ao buscar o card %d gerado pela recorrência %d: %v", card.ID, r.ID, err)
+		return true, nil
+	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_CREATED", Payload: created})
+	if wipExceeded != nil {
+		wipExceeded.CardID = card.ID
+		app.broadcast(boardID, WsMessage{Type: "WIP_EXCEEDED", Payload: wipExceeded})
+	}
+	return true, nil
+}
+
+// agendador dos cards recorrentes, de minuto em minuto
+func (app *App) runRecurringCards(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		spawned, err := app.spawnDueRecurrences(ctx, time.Now())
+		if err != nil {
+			log.Printf("Erro ao gerar cards recorrentes: %v", err)
+		}
+		if spawned > 0 {
+			log.Printf("Cards recorrentes gerados: %d", spawned)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
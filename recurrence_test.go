@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestRecurrenceNext(t *testing.T) {
+	loc := time.UTC
+	// quarta-feira, 15/01/2025 10:30
+	after := time.Date(2025, 1, 15, 10, 30, 0, 0, loc)
+	cases := []struct {
+		name string
+		rule CardRecurrence
+		want time.Time
+	}{
+		{"diaria ainda hoje", CardRecurrence{Frequency: RecurrenceDaily, TimeOfDay: "18:00"}, time.Date(2025, 1, 15, 18, 0, 0, 0, loc)},
+		{"diaria amanha", CardRecurrence{Frequency: RecurrenceDaily, TimeOfDay: "08:00"}, time.Date(2025, 1, 16, 8, 0, 0, 0, loc)},
+		{"semanal segunda e sexta", CardRecurrence{Frequency: RecurrenceWeekly, Weekdays: []int{1, 5}, TimeOfDay: "09:00"}, time.Date(2025, 1, 17, 9, 0, 0, 0, loc)},
+		{"semanal mesmo dia ja passou", CardRecurrence{Frequency: RecurrenceWeekly, Weekdays: []int{3}, TimeOfDay: "09:00"}, time.Date(2025, 1, 22, 9, 0, 0, 0, loc)},
+		{"mensal dia 31", CardRecurrence{Frequency: RecurrenceMonthly, MonthDay: intPtr(31), TimeOfDay: "08:00"}, time.Date(2025, 1, 31, 8, 0, 0, 0, loc)},
+		{"cron a cada 15 min", CardRecurrence{Frequency: RecurrenceCron, CronExpr: "*/15 * * * *"}, time.Date(2025, 1, 15, 10, 45, 0, 0, loc)},
+		{"cron dias uteis 7h", CardRecurrence{Frequency: RecurrenceCron, CronExpr: "0 7 * * 1-5"}, time.Date(2025, 1, 16, 7, 0, 0, 0, loc)},
+		{"cron primeiro do mes", CardRecurrence{Frequency: RecurrenceCron, CronExpr: "0 6 1 * *"}, time.Date(2025, 2, 1, 6, 0, 0, 0, loc)},
+		{"cron domingo como 7", CardRecurrence{Frequency: RecurrenceCron, CronExpr: "30 20 * * 7"}, time.Date(2025, 1, 19, 20, 30, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		got, err := tc.rule.next(after)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("%s: esperado %v, veio %v", tc.name, tc.want, got)
+		}
+	}
+
+	// fevereiro curto cai no ultimo dia
+	feb := CardRecurrence{Frequency: RecurrenceMonthly, MonthDay: intPtr(31), TimeOfDay: "08:00"}
+	got, _ := feb.next(time.Date(2025, 1, 31, 9, 0, 0, 0, loc))
+	if want := time.Date(2025, 2, 28, 8, 0, 0, 0, loc); !got.Equal(want) {
+		t.Fatalf("mensal em fevereiro: esperado %v, veio %v", want, got)
+	}
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	invalid := []CardRecurrence{
+		{Frequency: "anual"},
+		{Frequency: RecurrenceWeekly},
+		{Frequency: RecurrenceWeekly, Weekdays: []int{7}},
+		{Frequency: RecurrenceMonthly, MonthDay: intPtr(32)},
+		{Frequency: RecurrenceDaily, TimeOfDay: "25:00"},
+		{Frequency: RecurrenceCron, CronExpr: "* * *"},
+		{Frequency: RecurrenceCron, CronExpr: "60 * * * *"},
+		{Frequency: RecurrenceDaily, DueInDays: intPtr(-1)},
+	}
+	for _, r := range invalid {
+		if msg := r.validate(); msg == "" {
+			t.Fatalf("regra deveria ser inválida: %+v", r)
+		}
+	}
+	ok := CardRecurrence{Frequency: RecurrenceDaily}
+	if msg := ok.validate(); msg != "" || ok.TimeOfDay != "08:00" {
+		t.Fatalf("regra diária padrão deveria ser válida: %q %+v", msg, ok)
+	}
+}
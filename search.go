@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// marcadores usados no ts_headline; viram <mark> depois do escape do HTML
+const (
+	searchMarkStart = "\x02"
+	searchMarkStop  = "\x03"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// estrutura searchresult
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      string  `json:"id"`
+	BoardID *int    `json:"board_id,omitempty"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+// cards so aparecem se o usuario passaria no checkBoardPermission do board;
+// arquivados ficam de fora, a menos que $6 (include_archived) seja true
+const searchQuery = `
+WITH q AS (SELECT websearch_to_tsquery('portuguese', $1) AS query),
+hits AS (
+	SELECT 'card' AS type, ca.id::text AS id, col.board_id AS board_id, ca.title AS title,
+	       ts_headline('portuguese', coalesce(ca.title, '') || ' ' || coalesce(ca.description, ''), q.query, $5) AS snippet,
+	       ts_rank(ca.search_vector, q.query) AS rank
+	FROM cards ca
+	JOIN columns col ON col.id = ca.column_id
+	JOIN boards b ON b.id = col.board_id, q
+	WHERE ca.search_vector @@ q.query
+	  AND ca.deleted_at IS NULL AND col.deleted_at IS NULL AND b.deleted_at IS NULL
+	  AND ($6 OR ca.archived_at IS NULL)
+	  AND (b.is_public OR b.owner_id = $2
+	       OR EXISTS (SELECT 1 FROM board_memberships bm WHERE bm.board_id = b.id AND bm.user_id = $2))
+	UNION ALL
+	SELECT 'contato', cs.contato_id, NULL, cs.contato_id,
+	       ts_headline('portuguese', coalesce(cs.anotacao, ''), q.query, $5),
+	       ts_rank(cs.search_vector, q.query)
+	FROM contato_status cs, q
+	WHERE cs.search_vector @@ q.query
+	UNION ALL
+	SELECT 'avaliacao', a.id::text, NULL, a.customer_name,
+	       ts_headline('portuguese', coalesce(a.review_content, ''), q.query, $5),
+	       ts_rank(a.search_vector, q.query)
+	FROM avaliacoes a, q
+	WHERE a.search_vector @@ q.query
+	UNION ALL
+	SELECT 'ligacao', l.id::text, NULL, l.name,
+	       ts_headline('portuguese', coalesce(l.name, '') || ' ' || coalesce(l.observations, ''), q.query, $5),
+	       ts_rank(l.search_vector, q.query)
+	FROM ligacoes l, q
+	WHERE l.search_vector @@ q.query
+)
+SELECT type, id, board_id, title, snippet, rank, COUNT(*) OVER () AS total
+FROM hits
+ORDER BY rank DESC, type, id
+LIMIT $3 OFFSET $4`
+
+// escapar o snippet e trocar os marcadores por <mark>
+func highlightSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, searchMarkStart, "<mark>")
+	return strings.ReplaceAll(escaped, searchMarkStop, "</mark>")
+}
+
+// endpoint busca
+func (app *App) search(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O parâmetro q é obrigatório"})
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	includeArchived := c.QueryBool("include_archived", false)
+
+	headlineOptions := "StartSel=" + searchMarkStart + ", StopSel=" + searchMarkStop + ", MaxWords=30, MinWords=10, MaxFragments=2"
+	rows, err := app.db.Query(context.Background(), searchQuery, q, userID, limit, offset, headlineOptions, includeArchived)
+	if err != nil {
+		log.Printf("Erro na busca por '%s': %v", q, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao realizar a busca"})
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0)
+	var total int64
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Type, &r.ID, &r.BoardID, &r.Title, &r.Snippet, &r.Rank, &total); err != nil {
+			log.Printf("Erro ao ler resultado da busca: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao ler resultados da busca"})
+		}
+		r.Snippet = highlightSnippet(r.Snippet)
+		results = append(results, r)
+	}
+	return c.JSON(fiber.Map{
+		"results": results,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// arquivo nao existe no backend de armazenamento
+var ErrObjectNotFound = errors.New("objeto não encontrado")
+
+// imagens aceitas no upload de avatar e de ligacao, com a extensao gravada
+var imageUploadTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// tipo real da imagem pelo conteudo; o Content-Type e o nome enviados pelo cliente nao valem
+func sniffImage(data []byte) (contentType, ext string, ok bool) {
+	contentType = http.DetectContentType(data)
+	ext, ok = imageUploadTypes[contentType]
+	return contentType, ext, ok
+}
+
+// ler o arquivo do formulario inteiro
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return io.ReadAll(src)
+}
+
+// Storage abstrai onde os arquivos enviados ficam guardados
+type Storage interface {
+	// Put grava (ou substitui) o objeto e devolve a URL publica, se houver
+	Put(ctx context.Context, bucket, key, contentType string, data []byte) (string, error)
+	// Get abre o objeto para leitura
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	// Delete remove o objeto; remover um objeto inexistente nao e erro
+	Delete(ctx context.Context, bucket, key string) error
+}
+
+// escolher backend por STORAGE_BACKEND (supabase | local)
+func newStorageFromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "supabase":
+		return &SupabaseStorage{
+			ProjectURL: os.Getenv("SUPABASE_PROJECT_URL"),
+			ServiceKey: os.Getenv("SUPABASE_SERVICE_KEY"),
+			Client:     &http.Client{},
+		}, nil
+	case "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		return &LocalStorage{Root: dir, PublicPrefix: "/uploads"}, nil
+	default:
+		return nil, fmt.Errorf("STORAGE_BACKEND desconhecido: %s", backend)
+	}
+}
+
+// estrutura supabasestorage
+type SupabaseStorage struct {
+	ProjectURL string
+	ServiceKey string
+	Client     *http.Client
+}
+
+func (s *SupabaseStorage) objectURL(bucket, key string) string {
+	return fmt.Sprintf("%s/storage/v1/object/%s/%s", s.ProjectURL, bucket, key)
+}
+
+func (s *SupabaseStorage) Put(ctx context.Context, bucket, key, contentType string, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.objectURL(bucket, key), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar requisição para o Supabase: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-upsert", "true")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("erro ao fazer upload para o Supabase: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Supabase retornou status não-OK: %s, Body: %s", resp.Status, string(body))
+	}
+	return fmt.Sprintf("%s/storage/v1/object/public/%s/%s", s.ProjectURL, bucket, key), nil
+}
+
+func (s *SupabaseStorage) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao baixar do Supabase: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Supabase retornou status não-OK: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (s *SupabaseStorage) Delete(ctx context.Context, bucket, key string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", s.objectURL(bucket, key), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao remover do Supabase: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Supabase retornou status não-OK: %s", resp.Status)
+	}
+	return nil
+}
+
+// estrutura localstorage, grava em disco para desenvolvimento e testes
+type LocalStorage struct {
+	Root         string
+	PublicPrefix string
+}
+
+// caminho seguro dentro de Root
+func (s *LocalStorage) path(bucket, key string) (string, error) {
+	clean := filepath.Clean(filepath.Join(bucket, filepath.FromSlash(key)))
+	if !strings.HasPrefix(clean, filepath.Clean(bucket)+string(filepath.Separator)) {
+		return "", fmt.Errorf("chave de objeto inválida: %s/%s", bucket, key)
+	}
+	return filepath.Join(s.Root, clean), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, bucket, key, contentType string, data []byte) (string, error) {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return s.PublicPrefix + "/" + bucket + "/" + (&url.URL{Path: key}).EscapedPath(), nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, bucket, key string) error {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func TestSniffImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	contentType, ext, ok := sniffImage(buf.Bytes())
+	if !ok || contentType != "image/png" || ext != ".png" {
+		t.Fatalf("png deveria ser aceito como .png: %q %q %v", contentType, ext, ok)
+	}
+
+	// o que importa e o conteudo, nao o nome nem o Content-Type do cliente
+	for _, data := range [][]byte{
+		[]byte("<!DOCTYPE html><script>alert(1)</script>"),
+		[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\" onload=\"alert(1)\"/>"),
+		[]byte("texto qualquer"),
+	} {
+		if contentType, _, ok := sniffImage(data); ok {
+			t.Fatalf("conteúdo não-imagem aceito como %s: %q", contentType, data)
+		}
+	}
+}
+
+func TestAttachmentContentType(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	zip := []byte("PK\x03\x04conteudo")
+	ole := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 32)...)
+	docx := "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+	cases := []struct {
+		name     string
+		declared string
+		data     []byte
+		want     string
+		ok       bool
+	}{
+		{"png declarado como texto", "text/plain", buf.Bytes(), "image/png", true},
+		{"pdf sem tipo", "", []byte("%PDF-1.7\n..."), "application/pdf", true},
+		{"texto com charset", "text/plain; charset=latin1", []byte("olá"), "text/plain", true},
+		{"csv", "text/csv", []byte("a,b\n1,2\n"), "text/csv", true},
+		{"docx e um zip", docx, zip, docx, true},
+		{"zip", "application/zip", zip, "application/zip", true},
+		{"xls antigo", "application/vnd.ms-excel", ole, "application/vnd.ms-excel", true},
+		{"docx que nao e zip", docx, []byte("texto"), "text/plain", true},
+		{"html declarado como texto", "text/plain", []byte("<!DOCTYPE html><script>alert(1)</script>"), "text/html", false},
+		{"svg com xml", "image/svg+xml", []byte(`<?xml version="1.0"?><svg onload="alert(1)"/>`), "text/xml", false},
+		{"ole sem tipo do office", "", ole, oleContentType, false},
+		{"binario qualquer", "image/png", []byte{0x00, 0x01, 0x02}, "application/octet-stream", false},
+	}
+	for _, tc := range cases {
+		got, ok := attachmentContentType(tc.declared, tc.data)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("%s: esperado %s (%v), veio %s (%v)", tc.name, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s := &LocalStorage{Root: t.TempDir(), PublicPrefix: "/uploads"}
+	ctx := context.Background()
+
+	url, err := s.Put(ctx, "attachments", "card-1/a b.txt", "text/plain", []byte("primeiro"))
+	if err != nil || url != "/uploads/attachments/card-1/a%20b.txt" {
+		t.Fatalf("put: %q %v", url, err)
+	}
+	// put substitui o objeto
+	if _, err := s.Put(ctx, "attachments", "card-1/a b.txt", "text/plain", []byte("segundo")); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := s.Get(ctx, "attachments", "card-1/a b.txt")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "segundo" {
+		t.Fatalf("conteúdo lido: %q", data)
+	}
+
+	if err := s.Delete(ctx, "attachments", "card-1/a b.txt"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := s.Delete(ctx, "attachments", "card-1/a b.txt"); err != nil {
+		t.Fatalf("remover objeto inexistente não é erro: %v", err)
+	}
+	if _, err := s.Get(ctx, "attachments", "card-1/a b.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("objeto removido deveria dar ErrObjectNotFound: %v", err)
+	}
+
+	// chaves que escapam do bucket sao recusadas
+	for _, key := range []string{"../avatars/x.png", "card-1/../../x", ""} {
+		if _, err := s.Put(ctx, "attachments", key, "text/plain", []byte("x")); err == nil {
+			t.Fatalf("chave %q deveria ser recusada", key)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// registro inexistente em qualquer store
+var ErrNotFound = errors.New("registro não encontrado")
+
+// erros de regra das escritas de cards e colunas; o handler traduz para o status
+var (
+	ErrColumnNotEmpty   = errors.New("a coluna contém tarefas")
+	ErrCardColumnChange = errors.New("mudança de coluna fora do /cards/move")
+	ErrCardArchived     = errors.New("card arquivado")
+)
+
+// BoardStore acessa boards, colunas (para resolver o board) e membros
+type BoardStore interface {
+	GetBoard(ctx context.Context, boardID int) (Board, error)
+	ListPublicBoards(ctx context.Context) ([]Board, error)
+	// boards privados dos quais o usuario e dono
+	ListOwnedBoards(ctx context.Context, userID string) ([]Board, error)
+	// boards de outros donos onde o usuario e membro; OwnerName fica para quem chama
+	ListSharedBoards(ctx context.Context, userID string) ([]Board, error)
+	// papel do membro convidado; ErrNotFound se nao for membro
+	BoardMemberRole(ctx context.Context, boardID int, userID string) (BoardRole, error)
+	// membros convidados, sem o dono
+	ListBoardMembers(ctx context.Context, boardID int) ([]BoardMembership, error)
+	SetBoardMemberRole(ctx context.Context, boardID int, userID string, role BoardRole) error
+	// o novo dono precisa ser membro; o antigo vira editor
+	TransferBoardOwnership(ctx context.Context, boardID int, fromUserID, toUserID string) error
+	RemoveBoardMember(ctx context.Context, boardID int, userID string) error
+	// manda o board para a lixeira; colunas e cards somem junto ate a restauracao
+	DeleteBoard(ctx context.Context, boardID int, deletedBy string) error
+	// tira o board da lixeira; ErrNotFound se ele nao estiver la
+	RestoreBoard(ctx context.Context, boardID int) error
+	BoardIDForColumn(ctx context.Context, columnID int) (int, error)
+	BoardIDForCard(ctx context.Context, cardID int) (int, error)
+}
+
+// ColumnStore le e altera as colunas ativas de um board
+type ColumnStore interface {
+	// em ordem de posicao
+	ListColumns(ctx context.Context, boardID int) ([]Column, error)
+	// entra no fim do board, sem politica
+	CreateColumn(ctx context.Context, col Column) (Column, error)
+	// category vazia mantem a atual
+	UpdateColumn(ctx context.Context, columnID int, title, color string, category ColumnCategory) (Column, error)
+	SetColumnPolicy(ctx context.Context, columnID int, policy ColumnPolicy) (Column, error)
+	// manda para a lixeira e fecha o buraco na ordem; ErrColumnNotEmpty se ainda houver cards
+	DeleteColumn(ctx context.Context, columnID int, deletedBy string) error
+	// posicao = indice na lista; ids de outros boards sao ignorados
+	ReorderColumns(ctx context.Context, boardID int, orderedIDs []int) error
+}
+
+// CardStore le cards ja com labels e progresso do checklist e grava as escritas com o historico
+type CardStore interface {
+	GetCard(ctx context.Context, cardID int) (Card, error)
+	ListCards(ctx context.Context, columnID int, filter CardFilter) ([]Card, error)
+	// entra no fim da coluna; as regras barram com *ColumnPolicyViolation
+	CreateCard(ctx context.Context, card Card, actorID string) (Card, *WipExceeded, error)
+	// devolve o card como estava; trocar de coluna e ErrCardColumnChange
+	UpdateCard(ctx context.Context, cardID int, changes Card, actorID string) (Card, error)
+	// ErrCardArchived para card arquivado; as regras valem so ao trocar de coluna
+	MoveCard(ctx context.Context, cardID, columnID, position int, actorID string) (CardMove, error)
+	// manda para a lixeira; card inexistente nao e erro
+	DeleteCard(ctx context.Context, cardID int, actorID string) error
+}
+
+// CardMove e o resultado de um MoveCard
+type CardMove struct {
+	OldColumnID int
+	// limite suave estourado na coluna de destino
+	WipExceeded *WipExceeded
+}
+
+// CardFilter restringe a listagem de uma coluna
+type CardFilter struct {
+	// vazio = sem filtro; senao, cards com qualquer uma das labels
+	LabelIDs []int
+	// arquivados vem depois dos ativos, do mais antigo para o mais recente
+	IncludeArchived bool
+}
+
+// ContatoStore guarda status, anotacao e responsavel dos contatos
+type ContatoStore interface {
+	ListContatoStatus(ctx context.Context) ([]ContatoStatus, error)
+	// voltar para "pendente" libera o responsavel
+	SetContatoStatus(ctx context.Context, contatoID, status, anotacao, userID string) (int, error)
+	AssignContato(ctx context.Context, contatoID, assigneeID, updatedBy string) error
+	// sem force, so desassocia se o contato estiver com o proprio usuario
+	UnassignContato(ctx context.Context, contatoID, userID string, force bool) (bool, error)
+	ContatoExists(ctx context.Context, contatoID string) (bool, error)
+	SetContatoAnotacao(ctx context.Context, contatoID, anotacao, userID string) error
+}
+
+// NotificationStore le, cria e marca notificacoes; dentro de uma transacao use createNotification
+type NotificationStore interface {
+	CreateNotification(ctx context.Context, n Notification) error
+	ListNotifications(ctx context.Context, userID string) ([]Notification, error)
+	MarkNotificationRead(ctx context.Context, notificationID int, userID string) error
+	MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error)
+}
+
+// EventStore guarda os eventos de board para o replay na reconexao
+type EventStore interface {
+	// grava com o proximo seq do board e devolve esse seq
+	RecordBoardEvent(ctx context.Context, boardID int, message WsMessage) (int64, error)
+	// 0 para board sem eventos
+	LastBoardEventSeq(ctx context.Context, boardID int) (int64, error)
+	// eventos com since < seq <= until, em ordem; os expurgados pela retencao ficam de fora
+	ListBoardEvents(ctx context.Context, boardID int, since, until int64) ([]WsMessage, error)
+}
+
+// UserStore consulta os usuarios do provedor de identidade
+type UserStore interface {
+	GetUser(ctx context.Context, userID string) (User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	UserIDByUsername(ctx context.Context, username string) (string, error)
+}
+
+// ProfileStore guarda os perfis de exibicao (nome, avatar, telefone, equipe)
+type ProfileStore interface {
+	GetProfile(ctx context.Context, userID string) (Profile, error)
+	ListProfiles(ctx context.Context) ([]Profile, error)
+	// cria ou substitui o perfil inteiro
+	SaveProfile(ctx context.Context, profile Profile) (Profile, error)
+}
+
+// RoleStore guarda papeis, suas permissoes e os papeis de cada usuario
+type RoleStore interface {
+	ListRoles(ctx context.Context) ([]Role, error)
+	UserRoles(ctx context.Context, userID string) ([]string, error)
+	PermissionsForRoles(ctx context.Context, roles []string) ([]string, error)
+	// substitui todos os papeis do usuario
+	SetUserRoles(ctx context.Context, userID string, roles []string, assignedBy string) error
+}
+
+// apontar todos os stores do App para a mesma implementacao; usuarios vem de useIdentity
+func (app *App) useStores(s interface {
+	BoardStore
+	ColumnStore
+	CardStore
+	ContatoStore
+	NotificationStore
+	EventStore
+	ProfileStore
+	RoleStore
+}) {
+	app.boards = s
+	app.columns = s
+	app.cards = s
+	app.contatos = s
+	app.notifications = s
+	app.events = s
+	app.profiles = s
+	app.roles = s
+}
@@ -0,0 +1,847 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore implementa os stores em memoria, para testar handlers sem banco
+type MemoryStore struct {
+	mu            sync.RWMutex
+	nextID        int
+	users         map[string]User
+	boards        map[int]Board
+	trashedBoards map[int]Board
+	columns       map[int]Column
+	cards         map[int]Card
+	trashedCards  map[int]Card
+	members       map[int]map[string]BoardRole
+	cardLabels    map[int][]Label
+	contatos      map[string]ContatoStatus
+	notifications []Notification
+	activity      []CardActivity
+	events        map[int][]WsMessage
+	profiles      map[string]Profile
+	roles         []Role
+	userRoles     map[string]map[string]bool
+}
+
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         make(map[string]User),
+		boards:        make(map[int]Board),
+		trashedBoards: make(map[int]Board),
+		columns:       make(map[int]Column),
+		cards:         make(map[int]Card),
+		trashedCards:  make(map[int]Card),
+		members:       make(map[int]map[string]BoardRole),
+		cardLabels:    make(map[int][]Label),
+		contatos:      make(map[string]ContatoStatus),
+		events:        make(map[int][]WsMessage),
+		profiles:      make(map[string]Profile),
+		roles:         defaultRoles,
+		userRoles:     make(map[string]map[string]bool),
+	}
+}
+
+func (s *MemoryStore) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+// --- dados iniciais ---
+
+func (s *MemoryStore) AddUser(u User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	s.users[u.ID] = u
+	return u
+}
+
+func (s *MemoryStore) AddBoard(b Board) Board {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.ID == 0 {
+		b.ID = s.newID()
+	}
+	now := time.Now()
+	b.CreatedAt, b.UpdatedAt = now, now
+	s.boards[b.ID] = b
+	return b
+}
+
+func (s *MemoryStore) AddColumn(col Column) Column {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if col.ID == 0 {
+		col.ID = s.newID()
+	}
+	s.columns[col.ID] = col
+	return col
+}
+
+func (s *MemoryStore) AddCard(card Card) Card {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if card.ID == 0 {
+		card.ID = s.newID()
+	}
+	if card.Priority == "" {
+		card.Priority = "media"
+	}
+	now := time.Now()
+	card.CreatedAt, card.UpdatedAt = now, now
+	s.cards[card.ID] = card
+	if len(card.Labels) > 0 {
+		s.cardLabels[card.ID] = card.Labels
+	}
+	return card
+}
+
+func (s *MemoryStore) AddMember(boardID int, userID string, role BoardRole) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members[boardID] == nil {
+		s.members[boardID] = make(map[string]BoardRole)
+	}
+	s.members[boardID][userID] = role
+}
+
+func (s *MemoryStore) AddNotification(n Notification) Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n.ID == 0 {
+		n.ID = s.newID()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	s.notifications = append(s.notifications, n)
+	return n
+}
+
+// --- BoardStore ---
+
+func (s *MemoryStore) GetBoard(ctx context.Context, boardID int) (Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.boards[boardID]
+	if !ok {
+		return Board{}, ErrNotFound
+	}
+	return b, nil
+}
+
+func (s *MemoryStore) filterBoards(keep func(Board) bool) []Board {
+	boards := make([]Board, 0)
+	for _, b := range s.boards {
+		if keep(b) {
+			boards = append(boards, b)
+		}
+	}
+	sort.Slice(boards, func(i, j int) bool { return boards[i].CreatedAt.After(boards[j].CreatedAt) })
+	return boards
+}
+
+func (s *MemoryStore) ListPublicBoards(ctx context.Context) ([]Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	boards := s.filterBoards(func(b Board) bool { return b.IsPublic })
+	if len(boards) > 1 {
+		boards = boards[:1]
+	}
+	return boards, nil
+}
+
+func (s *MemoryStore) ListOwnedBoards(ctx context.Context, userID string) ([]Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filterBoards(func(b Board) bool { return b.OwnerID == userID && !b.IsPublic }), nil
+}
+
+func (s *MemoryStore) ListSharedBoards(ctx context.Context, userID string) ([]Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filterBoards(func(b Board) bool { return b.OwnerID != userID && s.members[b.ID][userID] != "" }), nil
+}
+
+func (s *MemoryStore) BoardMemberRole(ctx context.Context, boardID int, userID string) (BoardRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	role, ok := s.members[boardID][userID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return role, nil
+}
+
+func (s *MemoryStore) ListBoardMembers(ctx context.Context, boardID int) ([]BoardMembership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	members := make([]BoardMembership, 0)
+	for id, role := range s.members[boardID] {
+		if id != s.boards[boardID].OwnerID {
+			members = append(members, BoardMembership{UserID: id, Role: role})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members, nil
+}
+
+func (s *MemoryStore) SetBoardMemberRole(ctx context.Context, boardID int, userID string, role BoardRole) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[boardID][userID]; !ok {
+		return ErrNotFound
+	}
+	s.members[boardID][userID] = role
+	return nil
+}
+
+func (s *MemoryStore) TransferBoardOwnership(ctx context.Context, boardID int, fromUserID, toUserID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	board, ok := s.boards[boardID]
+	if _, member := s.members[boardID][toUserID]; !ok || !member || board.OwnerID != fromUserID {
+		return ErrNotFound
+	}
+	delete(s.members[boardID], toUserID)
+	s.members[boardID][fromUserID] = BoardRoleEditor
+	board.OwnerID = toUserID
+	board.UpdatedAt = time.Now()
+	s.boards[boardID] = board
+	return nil
+}
+
+func (s *MemoryStore) RemoveBoardMember(ctx context.Context, boardID int, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members[boardID], userID)
+	return nil
+}
+
+func (s *MemoryStore) DeleteBoard(ctx context.Context, boardID int, deletedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.boards[boardID]; ok {
+		s.trashedBoards[boardID] = b
+		delete(s.boards, boardID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) RestoreBoard(ctx context.Context, boardID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.trashedBoards[boardID]
+	if !ok {
+		return ErrNotFound
+	}
+	s.boards[boardID] = b
+	delete(s.trashedBoards, boardID)
+	return nil
+}
+
+func (s *MemoryStore) BoardIDForColumn(ctx context.Context, columnID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	col, ok := s.columns[columnID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return col.BoardID, nil
+}
+
+func (s *MemoryStore) BoardIDForCard(ctx context.Context, cardID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	card, ok := s.cards[cardID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	col, ok := s.columns[card.ColumnID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return col.BoardID, nil
+}
+
+// --- ColumnStore ---
+
+func (s *MemoryStore) ListColumns(ctx context.Context, boardID int) ([]Column, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	columns := make([]Column, 0)
+	for _, col := range s.columns {
+		if col.BoardID == boardID {
+			columns = append(columns, col)
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Position < columns[j].Position })
+	return columns, nil
+}
+
+func (s *MemoryStore) CreateColumn(ctx context.Context, col Column) (Column, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col.Position = 0
+	for _, other := range s.columns {
+		if other.BoardID == col.BoardID && other.Position >= col.Position {
+			col.Position = other.Position + 1
+		}
+	}
+	col.ID = s.newID()
+	col.ColumnPolicy = ColumnPolicy{}
+	s.columns[col.ID] = col
+	return col, nil
+}
+
+func (s *MemoryStore) UpdateColumn(ctx context.Context, columnID int, title, color string, category ColumnCategory) (Column, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.columns[columnID]
+	if !ok {
+		return Column{}, ErrNotFound
+	}
+	col.Title, col.Color = title, color
+	if category != "" {
+		col.Category = category
+	}
+	s.columns[columnID] = col
+	return col, nil
+}
+
+func (s *MemoryStore) SetColumnPolicy(ctx context.Context, columnID int, policy ColumnPolicy) (Column, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.columns[columnID]
+	if !ok {
+		return Column{}, ErrNotFound
+	}
+	col.ColumnPolicy = policy
+	s.columns[columnID] = col
+	return col, nil
+}
+
+func (s *MemoryStore) DeleteColumn(ctx context.Context, columnID int, deletedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.columns[columnID]
+	if !ok {
+		return ErrNotFound
+	}
+	// arquivados tambem contam, como no PgStore
+	for _, card := range s.cards {
+		if card.ColumnID == columnID {
+			return ErrColumnNotEmpty
+		}
+	}
+	delete(s.columns, columnID)
+	for id, other := range s.columns {
+		if other.BoardID == col.BoardID && other.Position > col.Position {
+			other.Position--
+			s.columns[id] = other
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ReorderColumns(ctx context.Context, boardID int, orderedIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, id := range orderedIDs {
+		if col, ok := s.columns[id]; ok && col.BoardID == boardID {
+			col.Position = i
+			s.columns[id] = col
+		}
+	}
+	return nil
+}
+
+// --- CardStore ---
+
+func (s *MemoryStore) GetCard(ctx context.Context, cardID int) (Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	card, ok := s.cards[cardID]
+	if !ok {
+		return Card{}, ErrNotFound
+	}
+	card.Labels = s.cardLabels[cardID]
+	return card, nil
+}
+
+func (s *MemoryStore) ListCards(ctx context.Context, columnID int, filter CardFilter) ([]Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cards := make([]Card, 0)
+	for _, card := range s.cards {
+		if card.ColumnID != columnID || (card.ArchivedAt != nil && !filter.IncludeArchived) {
+			continue
+		}
+		card.Labels = s.cardLabels[card.ID]
+		if len(filter.LabelIDs) > 0 && !hasAnyLabel(card.Labels, filter.LabelIDs) {
+			continue
+		}
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := cards[i].ArchivedAt, cards[j].ArchivedAt
+		switch {
+		case a == nil && b == nil:
+			return cards[i].Position < cards[j].Position
+		case a == nil || b == nil:
+			return a == nil
+		default:
+			return a.Before(*b)
+		}
+	})
+	return cards, nil
+}
+
+func hasAnyLabel(labels []Label, ids []int) bool {
+	for _, l := range labels {
+		for _, id := range ids {
+			if l.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cards ativos (nem arquivados nem na lixeira) da coluna, sem o card informado
+func (s *MemoryStore) activeCards(columnID, exceptID int) []Card {
+	cards := make([]Card, 0)
+	for _, card := range s.cards {
+		if card.ColumnID == columnID && card.ID != exceptID && card.ArchivedAt == nil {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// deslocar as posicoes dos cards ativos da coluna a partir de from
+func (s *MemoryStore) shiftCards(columnID, exceptID, from, delta int) {
+	for _, card := range s.activeCards(columnID, exceptID) {
+		if card.Position >= from {
+			card.Position += delta
+			s.cards[card.ID] = card
+		}
+	}
+}
+
+func (s *MemoryStore) recordActivity(a CardActivity) {
+	a.ID = int64(len(s.activity) + 1)
+	a.CreatedAt = time.Now()
+	s.activity = append(s.activity, a)
+}
+
+func (s *MemoryStore) CreateCard(ctx context.Context, card Card, actorID string) (Card, *WipExceeded, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.columns[card.ColumnID]
+	if !ok {
+		return card, nil, ErrNotFound
+	}
+	others := s.activeCards(col.ID, 0)
+	violation, wipExceeded := evaluateColumnEntry(col.ID, col.ColumnPolicy, card, len(others))
+	if violation != nil {
+		return card, nil, violation
+	}
+	card.Position = 1
+	for _, other := range others {
+		if other.Position >= card.Position {
+			card.Position = other.Position + 1
+		}
+	}
+	card.ID = s.newID()
+	now := time.Now()
+	card.CreatedAt, card.UpdatedAt = now, now
+	card.CompletedAt, card.ArchivedAt = nil, nil
+	// card criado direto numa coluna de conclusao ja nasce concluido
+	if col.Category.done() {
+		card.CompletedAt = &now
+	}
+	card.Labels = nil
+	s.cards[card.ID] = card
+	s.recordActivity(CardActivity{CardID: card.ID, BoardID: col.BoardID, ActorID: actorID, Action: "created", NewValue: activityValue(card.Title)})
+	if wipExceeded != nil {
+		wipExceeded.CardID = card.ID
+	}
+	return card, wipExceeded, nil
+}
+
+func (s *MemoryStore) UpdateCard(ctx context.Context, cardID int, changes Card, actorID string) (Card, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.cards[cardID]
+	if !ok {
+		return Card{}, ErrNotFound
+	}
+	if changes.ColumnID != 0 && changes.ColumnID != existing.ColumnID {
+		return existing, ErrCardColumnChange
+	}
+	col := s.columns[existing.ColumnID]
+	updated := existing
+	updated.Title, updated.Description, updated.AssignedTo = changes.Title, changes.Description, changes.AssignedTo
+	updated.Priority, updated.DueDate = changes.Priority, changes.DueDate
+	updated.UpdatedAt = time.Now()
+	// completed_at segue a categoria da coluna, como no MoveCard
+	if !col.Category.done() {
+		updated.CompletedAt = nil
+	} else if updated.CompletedAt == nil {
+		updated.CompletedAt = &updated.UpdatedAt
+	}
+	s.cards[cardID] = updated
+	for _, a := range cardChanges(cardID, col.BoardID, actorID, existing, updated) {
+		s.recordActivity(a)
+	}
+	return existing, nil
+}
+
+func (s *MemoryStore) MoveCard(ctx context.Context, cardID, columnID, position int, actorID string) (CardMove, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var move CardMove
+	card, ok := s.cards[cardID]
+	if !ok {
+		return move, ErrNotFound
+	}
+	if card.ArchivedAt != nil {
+		return move, ErrCardArchived
+	}
+	oldCol := s.columns[card.ColumnID]
+	newCol, ok := s.columns[columnID]
+	if !ok {
+		return move, ErrNotFound
+	}
+	move.OldColumnID = oldCol.ID
+
+	// as regras so valem para quem entra na coluna
+	if oldCol.ID != columnID {
+		var violation *ColumnPolicyViolation
+		violation, move.WipExceeded = evaluateColumnEntry(columnID, newCol.ColumnPolicy, card, len(s.activeCards(columnID, cardID)))
+		if violation != nil {
+			return move, violation
+		}
+	}
+
+	s.shiftCards(oldCol.ID, cardID, card.Position+1, -1)
+	s.shiftCards(columnID, cardID, position, 1)
+	card.ColumnID, card.Position = columnID, position
+	card.UpdatedAt = time.Now()
+	if newCol.Category.done() && !oldCol.Category.done() {
+		card.CompletedAt = &card.UpdatedAt
+	} else if oldCol.Category.done() && !newCol.Category.done() {
+		card.CompletedAt = nil
+	}
+	s.cards[cardID] = card
+
+	if oldCol.ID != columnID {
+		s.recordActivity(CardActivity{
+			CardID:   cardID,
+			BoardID:  newCol.BoardID,
+			ActorID:  actorID,
+			Action:   "moved",
+			Field:    "column",
+			OldValue: activityValue(oldCol.Title),
+			NewValue: activityValue(newCol.Title),
+		})
+	}
+	return move, nil
+}
+
+func (s *MemoryStore) DeleteCard(ctx context.Context, cardID int, actorID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	card, ok := s.cards[cardID]
+	if !ok {
+		return nil
+	}
+	delete(s.cards, cardID)
+	s.trashedCards[cardID] = card
+	if card.ArchivedAt == nil {
+		s.shiftCards(card.ColumnID, cardID, card.Position+1, -1)
+	}
+	s.recordActivity(CardActivity{CardID: cardID, BoardID: s.columns[card.ColumnID].BoardID, ActorID: actorID, Action: "deleted", OldValue: activityValue(card.Title)})
+	return nil
+}
+
+// --- ContatoStore ---
+
+func (s *MemoryStore) ListContatoStatus(ctx context.Context) ([]ContatoStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statuses := make([]ContatoStatus, 0, len(s.contatos))
+	for _, cs := range s.contatos {
+		statuses = append(statuses, cs)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses, nil
+}
+
+// contato existente ou um novo "pendente"; chamar com o lock
+func (s *MemoryStore) contato(contatoID string) ContatoStatus {
+	cs, ok := s.contatos[contatoID]
+	if !ok {
+		cs = ContatoStatus{ID: s.newID(), ContatoID: contatoID, Status: "pendente"}
+	}
+	return cs
+}
+
+func (s *MemoryStore) SetContatoStatus(ctx context.Context, contatoID, status, anotacao, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs := s.contato(contatoID)
+	cs.Status, cs.Anotacao, cs.UpdatedBy, cs.UpdatedAt = status, anotacao, userID, time.Now()
+	if status == "pendente" {
+		cs.AssignedTo = nil
+	}
+	s.contatos[contatoID] = cs
+	return cs.ID, nil
+}
+
+func (s *MemoryStore) AssignContato(ctx context.Context, contatoID, assigneeID, updatedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs := s.contato(contatoID)
+	cs.AssignedTo, cs.UpdatedBy, cs.UpdatedAt = &assigneeID, updatedBy, time.Now()
+	s.contatos[contatoID] = cs
+	return nil
+}
+
+func (s *MemoryStore) UnassignContato(ctx context.Context, contatoID, userID string, force bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs, ok := s.contatos[contatoID]
+	if !ok {
+		return false, nil
+	}
+	if !force && (cs.AssignedTo == nil || *cs.AssignedTo != userID) {
+		return false, nil
+	}
+	cs.AssignedTo, cs.UpdatedBy, cs.UpdatedAt = nil, userID, time.Now()
+	s.contatos[contatoID] = cs
+	return true, nil
+}
+
+func (s *MemoryStore) ContatoExists(ctx context.Context, contatoID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.contatos[contatoID]
+	return ok, nil
+}
+
+func (s *MemoryStore) SetContatoAnotacao(ctx context.Context, contatoID, anotacao, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cs := s.contato(contatoID)
+	cs.Anotacao, cs.UpdatedBy, cs.UpdatedAt = anotacao, userID, time.Now()
+	s.contatos[contatoID] = cs
+	return nil
+}
+
+// --- NotificationStore ---
+
+func (s *MemoryStore) CreateNotification(ctx context.Context, n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n.ID = s.newID()
+	n.CreatedAt = time.Now()
+	s.notifications = append(s.notifications, n)
+	return nil
+}
+
+func (s *MemoryStore) ListNotifications(ctx context.Context, userID string) ([]Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	notifications := make([]Notification, 0)
+	for _, n := range s.notifications {
+		if n.UserID == userID {
+			notifications = append(notifications, n)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		if notifications[i].IsRead != notifications[j].IsRead {
+			return !notifications[i].IsRead
+		}
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+	return notifications, nil
+}
+
+func (s *MemoryStore) MarkNotificationRead(ctx context.Context, notificationID int, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].ID == notificationID && s.notifications[i].UserID == userID {
+			s.notifications[i].IsRead = true
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for i := range s.notifications {
+		n := &s.notifications[i]
+		if n.UserID == userID && !n.IsRead && n.Type != "board_invitation" {
+			n.IsRead = true
+			count++
+		}
+	}
+	return count, nil
+}
+
+// --- EventStore ---
+
+func (s *MemoryStore) RecordBoardEvent(ctx context.Context, boardID int, message WsMessage) (int64, error) {
+	// payload serializado, como volta do board_events no PgStore
+	payload, err := json.Marshal(message.Payload)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message.Payload = json.RawMessage(payload)
+	message.Seq = int64(len(s.events[boardID]) + 1)
+	s.events[boardID] = append(s.events[boardID], message)
+	return message.Seq, nil
+}
+
+func (s *MemoryStore) LastBoardEventSeq(ctx context.Context, boardID int) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.events[boardID])), nil
+}
+
+func (s *MemoryStore) ListBoardEvents(ctx context.Context, boardID int, since, until int64) ([]WsMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := make([]WsMessage, 0)
+	for _, message := range s.events[boardID] {
+		if message.Seq > since && message.Seq <= until {
+			events = append(events, message)
+		}
+	}
+	return events, nil
+}
+
+// --- UserStore ---
+
+func (s *MemoryStore) GetUser(ctx context.Context, userID string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) ListUsers(ctx context.Context) ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (s *MemoryStore) UserIDByUsername(ctx context.Context, username string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.Username == username || u.Email == username {
+			return u.ID, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// --- ProfileStore ---
+
+func (s *MemoryStore) GetProfile(ctx context.Context, userID string) (Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[userID]
+	if !ok {
+		return Profile{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (s *MemoryStore) ListProfiles(ctx context.Context) ([]Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	profiles := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func (s *MemoryStore) SaveProfile(ctx context.Context, p Profile) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.UpdatedAt = time.Now()
+	s.profiles[p.UserID] = p
+	return p, nil
+}
+
+// --- RoleStore ---
+
+func (s *MemoryStore) ListRoles(ctx context.Context) ([]Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Role(nil), s.roles...), nil
+}
+
+func (s *MemoryStore) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	roles := make([]string, 0)
+	for role := range s.userRoles[userID] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (s *MemoryStore) PermissionsForRoles(ctx context.Context, roles []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wanted := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		wanted[r] = true
+	}
+	perms := make([]string, 0)
+	for _, role := range s.roles {
+		if wanted[role.Name] {
+			perms = append(perms, role.Permissions...)
+		}
+	}
+	return perms, nil
+}
+
+func (s *MemoryStore) SetUserRoles(ctx context.Context, userID string, roles []string, assignedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	set := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		set[r] = true
+	}
+	s.userRoles[userID] = set
+	return nil
+}
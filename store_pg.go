@@ -0,0 +1,949 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgStore implementa os stores sobre o pool do pgx
+type PgStore struct {
+	db *pgxpool.Pool
+}
+
+// colunas do card na ordem de scanCard
+const cardSelectColumns = `id, column_id, title, COALESCE(description, '') as description,
+	COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
+	due_date, position, created_at, updated_at, completed_at, archived_at`
+
+func scanCard(row pgx.Row, card *Card) error {
+	return row.Scan(&card.ID, &card.ColumnID, &card.Title, &card.Description,
+		&card.AssignedTo, &card.Priority, &card.DueDate, &card.Position,
+		&card.CreatedAt, &card.UpdatedAt, &card.CompletedAt, &card.ArchivedAt)
+}
+
+// traduzir pgx.ErrNoRows para o erro comum dos stores
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+const columnSelectColumns = `id, board_id, title, position, COALESCE(color, '#e4e6ea') AS color, category,
+	wip_limit, wip_soft, require_assignee, require_due_date`
+
+func scanColumn(row pgx.Row, col *Column) error {
+	return row.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color, &col.Category,
+		&col.WipLimit, &col.WipSoft, &col.RequireAssignee, &col.RequireDueDate)
+}
+
+const boardSelectColumns = `id, title, description, owner_id, created_at, updated_at, color, is_public`
+
+func scanBoard(row pgx.Row, board *Board) error {
+	return row.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID,
+		&board.CreatedAt, &board.UpdatedAt, &board.Color, &board.IsPublic)
+}
+
+func (s *PgStore) GetBoard(ctx context.Context, boardID int) (Board, error) {
+	var board Board
+	err := scanBoard(s.db.QueryRow(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE id = $1 AND deleted_at IS NULL", boardID), &board)
+	return board, notFound(err)
+}
+
+func (s *PgStore) queryBoards(ctx context.Context, query string, args ...interface{}) ([]Board, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	boards := make([]Board, 0)
+	for rows.Next() {
+		var board Board
+		if err := scanBoard(rows, &board); err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+	return boards, rows.Err()
+}
+
+func (s *PgStore) ListPublicBoards(ctx context.Context) ([]Board, error) {
+	return s.queryBoards(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE is_public = true AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 1")
+}
+
+func (s *PgStore) ListOwnedBoards(ctx context.Context, userID string) ([]Board, error) {
+	return s.queryBoards(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE owner_id = $1 AND is_public = false AND deleted_at IS NULL", userID)
+}
+
+func (s *PgStore) ListSharedBoards(ctx context.Context, userID string) ([]Board, error) {
+	query := `SELECT b.id, b.title, b.description, b.owner_id, b.created_at, b.updated_at, b.color, b.is_public
+	          FROM boards b
+	          JOIN board_memberships bm ON b.id = bm.board_id
+	          WHERE bm.user_id = $1 AND b.owner_id != $1 AND b.deleted_at IS NULL`
+	return s.queryBoards(ctx, query, userID)
+}
+
+func (s *PgStore) BoardMemberRole(ctx context.Context, boardID int, userID string) (BoardRole, error) {
+	var role BoardRole
+	err := s.db.QueryRow(ctx,
+		"SELECT role FROM board_memberships WHERE board_id = $1 AND user_id = $2",
+		boardID, userID).Scan(&role)
+	return role, notFound(err)
+}
+
+func (s *PgStore) ListBoardMembers(ctx context.Context, boardID int) ([]BoardMembership, error) {
+	query := `SELECT bm.user_id, bm.role FROM board_memberships bm
+	          JOIN boards b ON b.id = bm.board_id
+	          WHERE bm.board_id = $1 AND bm.user_id != b.owner_id
+	          ORDER BY bm.created_at`
+	rows, err := s.db.Query(ctx, query, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]BoardMembership, 0)
+	for rows.Next() {
+		var m BoardMembership
+		if err := rows.Scan(&m.UserID, &m.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *PgStore) SetBoardMemberRole(ctx context.Context, boardID int, userID string, role BoardRole) error {
+	tag, err := s.db.Exec(ctx,
+		"UPDATE board_memberships SET role = $1 WHERE board_id = $2 AND user_id = $3",
+		role, boardID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) TransferBoardOwnership(ctx context.Context, boardID int, fromUserID, toUserID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, toUserID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	tag, err = tx.Exec(ctx, "UPDATE boards SET owner_id = $1, updated_at = NOW() WHERE id = $2 AND owner_id = $3", toUserID, boardID, fromUserID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO board_memberships (board_id, user_id, role) VALUES ($1, $2, 'editor')
+		 ON CONFLICT (board_id, user_id) DO UPDATE SET role = 'editor'`,
+		boardID, fromUserID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PgStore) RemoveBoardMember(ctx context.Context, boardID int, userID string) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, userID)
+	return err
+}
+
+func (s *PgStore) DeleteBoard(ctx context.Context, boardID int, deletedBy string) error {
+	_, err := s.db.Exec(ctx, "UPDATE boards SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL", boardID, deletedBy)
+	return err
+}
+
+func (s *PgStore) RestoreBoard(ctx context.Context, boardID int) error {
+	tag, err := s.db.Exec(ctx, "UPDATE boards SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL", boardID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) BoardIDForColumn(ctx context.Context, columnID int) (int, error) {
+	var boardID int
+	err := s.db.QueryRow(ctx, "SELECT board_id FROM columns WHERE id = $1 AND deleted_at IS NULL", columnID).Scan(&boardID)
+	return boardID, notFound(err)
+}
+
+func (s *PgStore) BoardIDForCard(ctx context.Context, cardID int) (int, error) {
+	var boardID int
+	query := `SELECT c.board_id FROM columns c
+	          INNER JOIN cards ca ON c.id = ca.column_id
+	          WHERE ca.id = $1 AND ca.deleted_at IS NULL AND c.deleted_at IS NULL`
+	err := s.db.QueryRow(ctx, query, cardID).Scan(&boardID)
+	return boardID, notFound(err)
+}
+
+func (s *PgStore) ListColumns(ctx context.Context, boardID int) ([]Column, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+columnSelectColumns+" FROM columns WHERE board_id = $1 AND deleted_at IS NULL ORDER BY position", boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make([]Column, 0)
+	for rows.Next() {
+		var col Column
+		if err := scanColumn(rows, &col); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+func (s *PgStore) CreateColumn(ctx context.Context, col Column) (Column, error) {
+	var maxPos sql.NullInt64
+	err := s.db.QueryRow(ctx,
+		"SELECT MAX(position) FROM columns WHERE board_id = $1 AND deleted_at IS NULL", col.BoardID).Scan(&maxPos)
+	if err != nil || !maxPos.Valid {
+		maxPos.Int64 = -1
+	}
+	col.Position = int(maxPos.Int64) + 1
+	col.ColumnPolicy = ColumnPolicy{}
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO columns (board_id, title, position, color, category)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, col.BoardID, col.Title, col.Position, col.Color, col.Category).Scan(&col.ID)
+	return col, err
+}
+
+func (s *PgStore) UpdateColumn(ctx context.Context, columnID int, title, color string, category ColumnCategory) (Column, error) {
+	var col Column
+	err := scanColumn(s.db.QueryRow(ctx, `
+		UPDATE columns
+		SET title = $1, color = $2, category = COALESCE(NULLIF($3, ''), category)
+		WHERE id = $4 AND deleted_at IS NULL
+		RETURNING `+columnSelectColumns, title, color, string(category), columnID), &col)
+	return col, notFound(err)
+}
+
+func (s *PgStore) SetColumnPolicy(ctx context.Context, columnID int, policy ColumnPolicy) (Column, error) {
+	var col Column
+	err := scanColumn(s.db.QueryRow(ctx, `
+		UPDATE columns SET wip_limit = $1, wip_soft = $2, require_assignee = $3, require_due_date = $4
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING `+columnSelectColumns,
+		policy.WipLimit, policy.WipSoft, policy.RequireAssignee, policy.RequireDueDate, columnID), &col)
+	return col, notFound(err)
+}
+
+func (s *PgStore) DeleteColumn(ctx context.Context, columnID int, deletedBy string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var cardCount int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM cards WHERE column_id = $1 AND deleted_at IS NULL", columnID).Scan(&cardCount)
+	if err != nil {
+		return err
+	}
+	if cardCount > 0 {
+		return ErrColumnNotEmpty
+	}
+	// a coluna vai para a lixeira guardando a posicao, para a restauracao
+	var boardID, position int
+	err = tx.QueryRow(ctx,
+		"UPDATE columns SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL RETURNING board_id, position",
+		columnID, deletedBy).Scan(&boardID, &position)
+	if err != nil {
+		return notFound(err)
+	}
+	_, err = tx.Exec(ctx, "UPDATE columns SET position = position - 1 WHERE board_id = $1 AND position > $2 AND deleted_at IS NULL", boardID, position)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PgStore) ReorderColumns(ctx context.Context, boardID int, orderedIDs []int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	query := "UPDATE columns SET position = $1 WHERE id = $2 AND board_id = $3 AND deleted_at IS NULL"
+	for i, colID := range orderedIDs {
+		if _, err := tx.Exec(ctx, query, i, colID, boardID); err != nil {
+			return fmt.Errorf("coluna %d: %w", colID, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PgStore) GetCard(ctx context.Context, cardID int) (Card, error) {
+	var card Card
+	if err := scanCard(s.db.QueryRow(ctx, "SELECT "+cardSelectColumns+" FROM cards WHERE id = $1 AND deleted_at IS NULL", cardID), &card); err != nil {
+		return card, notFound(err)
+	}
+	cards := []Card{card}
+	if err := s.fillCardDetails(ctx, cards); err != nil {
+		return card, err
+	}
+	return cards[0], nil
+}
+
+func (s *PgStore) ListCards(ctx context.Context, columnID int, filter CardFilter) ([]Card, error) {
+	labelIDs := filter.LabelIDs
+	if len(labelIDs) == 0 {
+		labelIDs = nil
+	}
+	rows, err := s.db.Query(ctx, "SELECT "+cardSelectColumns+` FROM cards WHERE column_id = $1 AND deleted_at IS NULL
+		AND ($2::int[] IS NULL OR EXISTS (SELECT 1 FROM card_labels cl WHERE cl.card_id = cards.id AND cl.label_id = ANY($2)))
+		AND ($3 OR archived_at IS NULL)
+		ORDER BY archived_at NULLS FIRST, position`, columnID, labelIDs, filter.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cards := make([]Card, 0)
+	for rows.Next() {
+		var card Card
+		if err := scanCard(rows, &card); err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := s.fillCardDetails(ctx, cards); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// labels e contagem do checklist de uma leva de cards
+func (s *PgStore) fillCardDetails(ctx context.Context, cards []Card) error {
+	if len(cards) == 0 {
+		return nil
+	}
+	cardIDs := make([]int, len(cards))
+	for i := range cards {
+		cardIDs[i] = cards[i].ID
+	}
+	labels, err := s.loadCardLabels(ctx, cardIDs)
+	if err != nil {
+		return err
+	}
+	checklists, err := s.loadChecklistCounts(ctx, cardIDs)
+	if err != nil {
+		return err
+	}
+	for i := range cards {
+		cards[i].Labels = labels[cards[i].ID]
+		applyChecklistCounts(&cards[i], checklists[cards[i].ID])
+	}
+	return nil
+}
+
+func (s *PgStore) loadCardLabels(ctx context.Context, cardIDs []int) (map[int][]Label, error) {
+	query := `SELECT cl.card_id, l.id, l.board_id, l.name, l.color, l.created_at
+	          FROM card_labels cl JOIN board_labels l ON l.id = cl.label_id
+	          WHERE cl.card_id = ANY($1) ORDER BY l.name`
+	rows, err := s.db.Query(ctx, query, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	labels := make(map[int][]Label)
+	for rows.Next() {
+		var cardID int
+		var l Label
+		if err := rows.Scan(&cardID, &l.ID, &l.BoardID, &l.Name, &l.Color, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		labels[cardID] = append(labels[cardID], l)
+	}
+	return labels, rows.Err()
+}
+
+func (s *PgStore) loadChecklistCounts(ctx context.Context, cardIDs []int) (map[int][2]int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT card_id, COUNT(*), COUNT(*) FILTER (WHERE done)
+		FROM card_checklist_items WHERE card_id = ANY($1) GROUP BY card_id`, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[int][2]int)
+	for rows.Next() {
+		var cardID, total, done int
+		if err := rows.Scan(&cardID, &total, &done); err != nil {
+			return nil, err
+		}
+		counts[cardID] = [2]int{total, done}
+	}
+	return counts, rows.Err()
+}
+
+// board e categoria de uma coluna ativa, dentro da transacao
+func columnBoardAndCategory(ctx context.Context, tx pgx.Tx, columnID int) (int, ColumnCategory, error) {
+	var boardID int
+	var category ColumnCategory
+	err := tx.QueryRow(ctx, "SELECT board_id, category FROM columns WHERE id = $1 AND deleted_at IS NULL", columnID).Scan(&boardID, &category)
+	return boardID, category, notFound(err)
+}
+
+func (s *PgStore) CreateCard(ctx context.Context, card Card, actorID string) (Card, *WipExceeded, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return card, nil, err
+	}
+	defer tx.Rollback(ctx)
+	violation, wipExceeded, err := checkColumnEntry(ctx, tx, card.ColumnID, card)
+	if err != nil {
+		return card, nil, notFound(err)
+	}
+	if violation != nil {
+		return card, nil, violation
+	}
+	boardID, category, err := columnBoardAndCategory(ctx, tx, card.ColumnID)
+	if err != nil {
+		return card, nil, err
+	}
+	var maxPos sql.NullInt64
+	if err := tx.QueryRow(ctx, "SELECT MAX(position) FROM cards WHERE column_id = $1 AND deleted_at IS NULL AND archived_at IS NULL", card.ColumnID).Scan(&maxPos); err != nil {
+		return card, nil, err
+	}
+	card.Position = int(maxPos.Int64) + 1
+	// card criado direto numa coluna de conclusao ja nasce concluido
+	query := `INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $8 THEN NOW() END) RETURNING id, created_at, updated_at, completed_at`
+	err = tx.QueryRow(ctx, query, card.ColumnID, card.Title, card.Description, card.AssignedTo, card.Priority, card.DueDate, card.Position, category.done()).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt, &card.CompletedAt)
+	if err != nil {
+		return card, nil, err
+	}
+	if err := insertCardActivity(ctx, tx, CardActivity{CardID: card.ID, BoardID: boardID, ActorID: actorID, Action: "created", NewValue: activityValue(card.Title)}); err != nil {
+		return card, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return card, nil, err
+	}
+	if wipExceeded != nil {
+		wipExceeded.CardID = card.ID
+	}
+	return card, wipExceeded, nil
+}
+
+func (s *PgStore) UpdateCard(ctx context.Context, cardID int, changes Card, actorID string) (Card, error) {
+	var existing Card
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return existing, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT column_id, title, COALESCE(description, ''), COALESCE(assigned_to, ''), COALESCE(priority, 'media'), due_date, completed_at
+		FROM cards WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, cardID).Scan(
+		&existing.ColumnID, &existing.Title, &existing.Description, &existing.AssignedTo,
+		&existing.Priority, &existing.DueDate, &existing.CompletedAt)
+	if err != nil {
+		return existing, notFound(err)
+	}
+	existing.ID = cardID
+	if changes.ColumnID != 0 && changes.ColumnID != existing.ColumnID {
+		return existing, ErrCardColumnChange
+	}
+	changes.ColumnID = existing.ColumnID
+
+	// completed_at nao vem do cliente: segue a categoria da coluna, como no MoveCard
+	boardID, category, err := columnBoardAndCategory(ctx, tx, existing.ColumnID)
+	if err != nil {
+		return existing, err
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE cards SET
+			title = $1,
+			description = $2,
+			assigned_to = $3,
+			priority = $4,
+			due_date = $5,
+			completed_at = CASE WHEN $6 THEN COALESCE(completed_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $7
+		RETURNING completed_at`,
+		changes.Title, changes.Description, changes.AssignedTo, changes.Priority,
+		changes.DueDate, category.done(), cardID).Scan(&changes.CompletedAt)
+	if err != nil {
+		return existing, err
+	}
+	for _, a := range cardChanges(cardID, boardID, actorID, existing, changes) {
+		if err := insertCardActivity(ctx, tx, a); err != nil {
+			return existing, err
+		}
+	}
+	return existing, tx.Commit(ctx)
+}
+
+func (s *PgStore) MoveCard(ctx context.Context, cardID, columnID, position int, actorID string) (CardMove, error) {
+	var move CardMove
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return move, err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldPosition int
+	var oldColumnTitle, newColumnTitle string
+	var oldCategory, newCategory ColumnCategory
+	var archived bool
+	var boardID int
+	moving := Card{ID: cardID}
+	err = tx.QueryRow(ctx,
+		`SELECT c.column_id, c.position, col.title, col.category, c.archived_at IS NOT NULL, COALESCE(c.assigned_to, ''), c.due_date FROM cards c
+		 JOIN columns col ON c.column_id = col.id
+		 WHERE c.id = $1 AND c.deleted_at IS NULL FOR UPDATE OF c`, cardID,
+	).Scan(&move.OldColumnID, &oldPosition, &oldColumnTitle, &oldCategory, &archived, &moving.AssignedTo, &moving.DueDate)
+	if err != nil {
+		return move, notFound(err)
+	}
+	if archived {
+		return move, ErrCardArchived
+	}
+	err = tx.QueryRow(ctx, "SELECT board_id, title, category FROM columns WHERE id = $1 AND deleted_at IS NULL", columnID).Scan(&boardID, &newColumnTitle, &newCategory)
+	if err != nil {
+		return move, notFound(err)
+	}
+
+	// as regras so valem para quem entra na coluna
+	if move.OldColumnID != columnID {
+		var violation *ColumnPolicyViolation
+		violation, move.WipExceeded, err = checkColumnEntry(ctx, tx, columnID, moving)
+		if err != nil {
+			return move, err
+		}
+		if violation != nil {
+			return move, violation
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2 AND deleted_at IS NULL AND archived_at IS NULL",
+		move.OldColumnID, oldPosition)
+	if err != nil {
+		return move, err
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE cards SET position = position + 1 WHERE column_id = $1 AND position >= $2 AND deleted_at IS NULL AND archived_at IS NULL",
+		columnID, position)
+	if err != nil {
+		return move, err
+	}
+
+	completedAtUpdate := ""
+	if newCategory.done() && !oldCategory.done() {
+		completedAtUpdate = ", completed_at = NOW()"
+	} else if oldCategory.done() && !newCategory.done() {
+		completedAtUpdate = ", completed_at = NULL"
+	}
+	_, err = tx.Exec(ctx, "UPDATE cards SET column_id = $1, position = $2, updated_at = NOW()"+completedAtUpdate+" WHERE id = $3",
+		columnID, position, cardID)
+	if err != nil {
+		return move, err
+	}
+
+	if move.OldColumnID != columnID {
+		err = insertCardActivity(ctx, tx, CardActivity{
+			CardID:   cardID,
+			BoardID:  boardID,
+			ActorID:  actorID,
+			Action:   "moved",
+			Field:    "column",
+			OldValue: activityValue(oldColumnTitle),
+			NewValue: activityValue(newColumnTitle),
+		})
+		if err != nil {
+			return move, err
+		}
+	}
+	return move, tx.Commit(ctx)
+}
+
+func (s *PgStore) DeleteCard(ctx context.Context, cardID int, actorID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// o card vai para a lixeira guardando coluna e posicao, para a restauracao
+	var title string
+	var boardID, columnID, position int
+	var archived bool
+	err = tx.QueryRow(ctx,
+		`UPDATE cards SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL
+		 RETURNING title, column_id, position, archived_at IS NOT NULL, (SELECT board_id FROM columns WHERE id = cards.column_id)`,
+		cardID, actorID).Scan(&title, &columnID, &position, &archived, &boardID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !archived {
+		_, err = tx.Exec(ctx,
+			"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2 AND deleted_at IS NULL AND archived_at IS NULL",
+			columnID, position)
+		if err != nil {
+			return err
+		}
+	}
+	if err := insertCardActivity(ctx, tx, CardActivity{CardID: cardID, BoardID: boardID, ActorID: actorID, Action: "deleted", OldValue: activityValue(title)}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PgStore) ListContatoStatus(ctx context.Context) ([]ContatoStatus, error) {
+	rows, err := s.db.Query(ctx, `SELECT id, contato_id, status, anotacao, updated_at, updated_by, assigned_to FROM contato_status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	statuses := make([]ContatoStatus, 0)
+	for rows.Next() {
+		var cs ContatoStatus
+		var anotacao, updatedBy, assignedTo sql.NullString
+		if err := rows.Scan(&cs.ID, &cs.ContatoID, &cs.Status, &anotacao, &cs.UpdatedAt, &updatedBy, &assignedTo); err != nil {
+			return nil, err
+		}
+		cs.Anotacao = anotacao.String
+		cs.UpdatedBy = updatedBy.String
+		if assignedTo.Valid {
+			cs.AssignedTo = &assignedTo.String
+		}
+		statuses = append(statuses, cs)
+	}
+	return statuses, rows.Err()
+}
+
+func (s *PgStore) SetContatoStatus(ctx context.Context, contatoID, status, anotacao, userID string) (int, error) {
+	query := `
+        INSERT INTO contato_status (contato_id, status, anotacao, updated_at, updated_by, assigned_to)
+        VALUES (
+            $1, $2, $3, NOW(), $4,
+            CASE WHEN $2 = 'pendente' THEN NULL ELSE (SELECT assigned_to FROM contato_status WHERE contato_id = $1) END
+        )
+        ON CONFLICT (contato_id)
+        DO UPDATE SET
+            status = EXCLUDED.status,
+            anotacao = EXCLUDED.anotacao,
+            updated_at = NOW(),
+            updated_by = EXCLUDED.updated_by,
+            assigned_to = CASE WHEN EXCLUDED.status = 'pendente' THEN NULL ELSE contato_status.assigned_to END
+        RETURNING id
+    `
+	var id int
+	err := s.db.QueryRow(ctx, query, contatoID, status, anotacao, userID).Scan(&id)
+	return id, err
+}
+
+func (s *PgStore) AssignContato(ctx context.Context, contatoID, assigneeID, updatedBy string) error {
+	query := `
+        INSERT INTO contato_status (contato_id, status, updated_by, assigned_to)
+        VALUES ($1, 'pendente', $2, $3)
+        ON CONFLICT (contato_id)
+        DO UPDATE SET
+            assigned_to = EXCLUDED.assigned_to,
+            updated_at = NOW(),
+            updated_by = EXCLUDED.updated_by
+    `
+	_, err := s.db.Exec(ctx, query, contatoID, updatedBy, assigneeID)
+	return err
+}
+
+func (s *PgStore) UnassignContato(ctx context.Context, contatoID, userID string, force bool) (bool, error) {
+	query := `
+        UPDATE contato_status
+        SET
+            assigned_to = NULL,
+            updated_at = NOW(),
+            updated_by = $2::uuid
+        WHERE contato_id = $1`
+	if !force {
+		query += " AND assigned_to = $2"
+	}
+	cmdTag, err := s.db.Exec(ctx, query, contatoID, userID)
+	if err != nil {
+		return false, err
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (s *PgStore) ContatoExists(ctx context.Context, contatoID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM contato_status WHERE contato_id = $1)", contatoID).Scan(&exists)
+	return exists, err
+}
+
+func (s *PgStore) SetContatoAnotacao(ctx context.Context, contatoID, anotacao, userID string) error {
+	query := `
+        INSERT INTO contato_status (contato_id, status, anotacao, updated_by)
+        VALUES ($1, 'pendente', $2, $3)
+        ON CONFLICT (contato_id)
+        DO UPDATE SET
+            anotacao = EXCLUDED.anotacao,
+            updated_at = NOW(),
+            updated_by = EXCLUDED.updated_by
+    `
+	_, err := s.db.Exec(ctx, query, contatoID, anotacao, userID)
+	return err
+}
+
+func (s *PgStore) CreateNotification(ctx context.Context, n Notification) error {
+	_, err := s.db.Exec(ctx, notificationInsert,
+		n.UserID, n.Type, n.Message, n.RelatedBoardID, n.RelatedCardID, n.InvitationID)
+	return err
+}
+
+func (s *PgStore) ListNotifications(ctx context.Context, userID string) ([]Notification, error) {
+	query := `
+		SELECT
+			n.id, n.user_id, n.type, n.message, n.is_read, n.related_board_id, n.related_card_id,
+			n.invitation_id, n.created_at,
+			COALESCE(bi.status, '') as invitation_status
+		FROM notifications n
+		LEFT JOIN board_invitations bi ON n.invitation_id = bi.id
+		WHERE n.user_id = $1
+		ORDER BY n.is_read ASC, n.created_at DESC
+	`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &n.IsRead, &n.RelatedBoardID,
+			&n.RelatedCardID, &n.InvitationID, &n.CreatedAt, &n.InvitationStatus); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (s *PgStore) MarkNotificationRead(ctx context.Context, notificationID int, userID string) error {
+	_, err := s.db.Exec(ctx, "UPDATE notifications SET is_read = true WHERE id = $1 AND user_id = $2", notificationID, userID)
+	return err
+}
+
+func (s *PgStore) MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error) {
+	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE AND type != 'board_invitation'`
+	cmdTag, err := s.db.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+const profileSelectColumns = `user_id, display_name, avatar_url, phone, team, active, updated_at`
+
+func (s *PgStore) RecordBoardEvent(ctx context.Context, boardID int, message WsMessage) (int64, error) {
+	payload, err := json.Marshal(message.Payload)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var seq int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO board_event_seqs (board_id, last_seq) VALUES ($1, 1)
+		ON CONFLICT (board_id) DO UPDATE SET last_seq = board_event_seqs.last_seq + 1
+		RETURNING last_seq`, boardID).Scan(&seq)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO board_events (board_id, seq, message_id, type, sender_id, payload) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)`,
+		boardID, seq, message.ID, message.Type, message.SenderID, payload)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (s *PgStore) LastBoardEventSeq(ctx context.Context, boardID int) (int64, error) {
+	var lastSeq int64
+	err := s.db.QueryRow(ctx, "SELECT last_seq FROM board_event_seqs WHERE board_id = $1", boardID).Scan(&lastSeq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return lastSeq, err
+}
+
+func (s *PgStore) ListBoardEvents(ctx context.Context, boardID int, since, until int64) ([]WsMessage, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT seq, message_id, type, COALESCE(sender_id, ''), payload
+		FROM board_events WHERE board_id = $1 AND seq > $2 AND seq <= $3 ORDER BY seq`, boardID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]WsMessage, 0)
+	for rows.Next() {
+		var message WsMessage
+		var payload json.RawMessage
+		if err := rows.Scan(&message.Seq, &message.ID, &message.Type, &message.SenderID, &payload); err != nil {
+			return nil, err
+		}
+		message.Payload = payload
+		events = append(events, message)
+	}
+	return events, rows.Err()
+}
+
+func scanProfile(row pgx.Row, p *Profile) error {
+	return row.Scan(&p.UserID, &p.DisplayName, &p.AvatarURL, &p.Phone, &p.Team, &p.Active, &p.UpdatedAt)
+}
+
+func (s *PgStore) GetProfile(ctx context.Context, userID string) (Profile, error) {
+	var profile Profile
+	err := scanProfile(s.db.QueryRow(ctx, "SELECT "+profileSelectColumns+" FROM profiles WHERE user_id = $1", userID), &profile)
+	return profile, notFound(err)
+}
+
+func (s *PgStore) ListProfiles(ctx context.Context) ([]Profile, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+profileSelectColumns+" FROM profiles")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	profiles := make([]Profile, 0)
+	for rows.Next() {
+		var profile Profile
+		if err := scanProfile(rows, &profile); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+func (s *PgStore) SaveProfile(ctx context.Context, p Profile) (Profile, error) {
+	query := `INSERT INTO profiles (user_id, display_name, avatar_url, phone, team, active)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (user_id) DO UPDATE SET
+	              display_name = EXCLUDED.display_name,
+	              avatar_url = EXCLUDED.avatar_url,
+	              phone = EXCLUDED.phone,
+	              team = EXCLUDED.team,
+	              active = EXCLUDED.active,
+	              updated_at = NOW()
+	          RETURNING ` + profileSelectColumns
+	var saved Profile
+	err := scanProfile(s.db.QueryRow(ctx, query, p.UserID, p.DisplayName, p.AvatarURL, p.Phone, p.Team, p.Active), &saved)
+	return saved, err
+}
+
+func (s *PgStore) ListRoles(ctx context.Context) ([]Role, error) {
+	query := `SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	          FROM roles r
+	          LEFT JOIN role_permissions rp ON rp.role = r.name
+	          GROUP BY r.name, r.description
+	          ORDER BY r.name`
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := make([]Role, 0)
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *PgStore) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.Query(ctx, "SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := make([]string, 0)
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *PgStore) PermissionsForRoles(ctx context.Context, roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return []string{}, nil
+	}
+	rows, err := s.db.Query(ctx, "SELECT DISTINCT permission FROM role_permissions WHERE role = ANY($1)", roles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	perms := make([]string, 0)
+	for rows.Next() {
+		var perm string
+		if err := rows.Scan(&perm); err != nil {
+			return nil, err
+		}
+		perms = append(perms, perm)
+	}
+	return perms, rows.Err()
+}
+
+func (s *PgStore) SetUserRoles(ctx context.Context, userID string, roles []string, assignedBy string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "DELETE FROM user_roles WHERE user_id = $1 AND role <> ALL($2)", userID, roles); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO user_roles (user_id, role, assigned_by) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			userID, role, assignedBy)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// Os testes de integracao precisam de um Postgres descartavel:
+//   - TEST_DATABASE_URL aponta para um servidor existente (o usuario precisa de CREATEDB), ou
+//   - initdb/pg_ctl no PATH (ou em /usr/lib/postgresql/*/bin) sobem um servidor temporario.
+// Sem nenhum dos dois, os testes sao pulados.
+
+const testJWTSecret = "segredo-de-teste-taskhub"
+
+var testPG struct {
+	once    sync.Once
+	url     string
+	skip    string
+	cleanup func()
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testPG.cleanup != nil {
+		testPG.cleanup()
+	}
+	os.Exit(code)
+}
+
+// URL administrativa do servidor de teste; pula o teste se nao houver Postgres
+func testServerURL(t *testing.T) string {
+	t.Helper()
+	testPG.once.Do(func() {
+		if raw := os.Getenv("TEST_DATABASE_URL"); raw != "" {
+			testPG.url = raw
+			return
+		}
+		testPG.url, testPG.cleanup, testPG.skip = startLocalPostgres()
+	})
+	if testPG.url == "" {
+		t.Skipf("Postgres indisponível para testes de integração: %s", testPG.skip)
+	}
+	return testPG.url
+}
+
+func findPgBinary(name string) string {
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+	matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/" + name)
+	if len(matches) > 0 {
+		return matches[len(matches)-1]
+	}
+	return ""
+}
+
+// initdb + pg_ctl em um diretorio temporario, escutando so em 127.0.0.1
+func startLocalPostgres() (string, func(), string) {
+	initdb, pgCtl := findPgBinary("initdb"), findPgBinary("pg_ctl")
+	if initdb == "" || pgCtl == "" {
+		return "", nil, "defina TEST_DATABASE_URL ou instale initdb/pg_ctl"
+	}
+	if os.Geteuid() == 0 {
+		return "", nil, "initdb não roda como root; defina TEST_DATABASE_URL"
+	}
+	dir, err := os.MkdirTemp("", "taskhub-pg-")
+	if err != nil {
+		return "", nil, err.Error()
+	}
+	dataDir := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Sprintf("initdb falhou: %v: %s", err, out)
+	}
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err.Error()
+	}
+	opts := fmt.Sprintf("-h 127.0.0.1 -p %d -k %s -F", port, dir)
+	logFile := filepath.Join(dir, "postgres.log")
+	if out, err := exec.Command(pgCtl, "-D", dataDir, "-o", opts, "-l", logFile, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Sprintf("pg_ctl start falhou: %v: %s", err, out)
+	}
+	stop := func() {
+		exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, ""
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// estrutura testenv: App ligado a um banco novo, ja migrado
+type testEnv struct {
+	t     *testing.T
+	app   *App
+	fiber *fiber.App
+}
+
+// banco exclusivo por teste, removido no fim
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	serverURL := testServerURL(t)
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, serverURL)
+	if err != nil {
+		t.Fatalf("conectar ao Postgres de teste: %v", err)
+	}
+	dbName := "taskhub_test_" + randomHex(6)
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+dbName); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("criar banco de teste: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+dbName+" WITH (FORCE)")
+		admin.Close(context.Background())
+	})
+
+	dbURL, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("URL de teste inválida: %v", err)
+	}
+	dbURL.Path = "/" + dbName
+	t.Setenv("DATABASE_URL", dbURL.String())
+
+	app := &App{hub: newHub(), storage: &LocalStorage{Root: t.TempDir(), PublicPrefix: "/uploads"}}
+	if err := app.connectDB(); err != nil {
+		t.Fatalf("conectar ao banco de teste: %v", err)
+	}
+	t.Cleanup(app.db.Close)
+	app.useStores(&PgStore{db: app.db})
+	app.useIdentity(&SupabaseIdentity{db: app.db, JWTSecret: testJWTSecret})
+	if err := app.migrateUp(ctx); err != nil {
+		t.Fatalf("aplicar migrações: %v", err)
+	}
+	return &testEnv{t: t, app: app, fiber: app.newFiberApp()}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// usuario no stand-in de auth.users
+func (e *testEnv) createUser(username string, isAdmin bool) string {
+	e.t.Helper()
+	meta, _ := json.Marshal(map[string]interface{}{"username": username, "is_admin": isAdmin})
+	var id string
+	err := e.app.db.QueryRow(context.Background(),
+		"INSERT INTO auth.users (id, email, raw_user_meta_data) VALUES (gen_random_uuid(), $1, $2) RETURNING id",
+		username+"@teste.local", string(meta)).Scan(&id)
+	if err != nil {
+		e.t.Fatalf("criar usuário %s: %v", username, err)
+	}
+	// como na migracao: o flag legado so vale pelo papel admin
+	if isAdmin {
+		if _, err := e.app.db.Exec(context.Background(), "INSERT INTO user_roles (user_id, role) VALUES ($1, 'admin')", id); err != nil {
+			e.t.Fatalf("papel admin de %s: %v", username, err)
+		}
+	}
+	return id
+}
+
+// JWT no formato do Supabase, assinado com o segredo de teste
+func (e *testEnv) token(userID string) string {
+	e.t.Helper()
+	claims := SupabaseClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{"authenticated"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		e.t.Fatalf("assinar token: %v", err)
+	}
+	return signed
+}
+
+// requisicao autenticada como userID; devolve status e corpo cru
+func (e *testEnv) request(method, path, userID string, body interface{}) (int, []byte) {
+	e.t.Helper()
+	token := ""
+	if userID != "" {
+		token = e.token(userID)
+	}
+	return e.send(method, path, token, body)
+}
+
+// requisicao com um bearer token qualquer (vazio = sem Authorization)
+func (e *testEnv) send(method, path, token string, body interface{}) (int, []byte) {
+	e.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatalf("serializar corpo: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := e.fiber.Test(req, 10_000)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, raw
+}
+
+// upload multipart autenticado com um unico arquivo no campo field
+func (e *testEnv) upload(path, userID, field, filename, contentType string, data []byte) (int, []byte) {
+	e.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, filename))
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		e.t.Fatalf("montar upload: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+e.token(userID))
+	resp, err := e.fiber.Test(req, 10_000)
+	if err != nil {
+		e.t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, raw
+}
+
+// falha o teste se o status nao for o esperado; out recebe o JSON da resposta, se nao for nil
+func (e *testEnv) expect(status int, method, path, userID string, body interface{}, out interface{}) {
+	e.t.Helper()
+	got, raw := e.request(method, path, userID, body)
+	if got != status {
+		e.t.Fatalf("%s %s: status %d, esperado %d: %s", method, path, got, status, raw)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			e.t.Fatalf("%s %s: resposta não é JSON válido: %s", method, path, raw)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// retencao padrao da lixeira
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// estrutura trashitem
+type TrashItem struct {
+	Type          string    `json:"type"`
+	ID            int       `json:"id"`
+	Title         string    `json:"title"`
+	BoardID       int       `json:"board_id"`
+	BoardTitle    string    `json:"board_title"`
+	ColumnID      *int      `json:"column_id,omitempty"`
+	DeletedAt     time.Time `json:"deleted_at"`
+	DeletedBy     string    `json:"deleted_by"`
+	DeletedByName string    `json:"deleted_by_name"`
+	PurgeAt       time.Time `json:"purge_at"`
+}
+
+// itens que o usuario excluiu ou que estao em boards dele
+const trashQuery = `
+SELECT 'board' AS type, b.id, b.title, b.id, b.title, NULL::int, b.deleted_at, COALESCE(b.deleted_by::text, '')
+FROM boards b
+WHERE b.deleted_at IS NOT NULL AND (b.deleted_by = $1 OR b.owner_id = $1)
+UNION ALL
+SELECT 'column', col.id, col.title, b.id, b.title, NULL::int, col.deleted_at, COALESCE(col.deleted_by::text, '')
+FROM columns col
+JOIN boards b ON b.id = col.board_id
+WHERE col.deleted_at IS NOT NULL AND (col.deleted_by = $1 OR b.owner_id = $1)
+UNION ALL
+SELECT 'card', ca.id, ca.title, b.id, b.title, col.id, ca.deleted_at, COALESCE(ca.deleted_by::text, '')
+FROM cards ca
+JOIN columns col ON col.id = ca.column_id
+JOIN boards b ON b.id = col.board_id
+WHERE ca.deleted_at IS NOT NULL AND (ca.deleted_by = $1 OR b.owner_id = $1)
+ORDER BY 7 DESC`
+
+// retencao configuravel por TRASH_RETENTION (ex: 720h)
+func trashRetention() time.Duration {
+	if raw := os.Getenv("TRASH_RETENTION"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Aviso: TRASH_RETENTION inválido (%s), usando %s", raw, defaultTrashRetention)
+	}
+	return defaultTrashRetention
+}
+
+// endpoint lixeira
+func (app *App) getTrash(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	rows, err := app.db.Query(context.Background(), trashQuery, userID)
+	if err != nil {
+		log.Printf("Erro ao buscar lixeira de %s: %v", userID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar a lixeira"})
+	}
+	defer rows.Close()
+
+	retention := trashRetention()
+	items := make([]TrashItem, 0)
+	for rows.Next() {
+		var item TrashItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.Title, &item.BoardID, &item.BoardTitle,
+			&item.ColumnID, &item.DeletedAt, &item.DeletedBy); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler a lixeira"})
+		}
+		item.PurgeAt = item.DeletedAt.Add(retention)
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao ler a lixeira"})
+	}
+	rows.Close()
+	for i := range items {
+		if items[i].DeletedBy != "" {
+			items[i].DeletedByName = app.getDisplayName(context.Background(), items[i].DeletedBy)
+		}
+	}
+	return c.JSON(items)
+}
+
+// endpoint restaurar item da lixeira
+func (app *App) restoreTrashItem(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID inválido"})
+	}
+	userID := c.Locals("userID").(string)
+	switch c.Params("type") {
+	case "board":
+		return app.restoreBoard(c, userID, id)
+	case "column":
+		return app.restoreColumn(c, userID, id)
+	case "card":
+		return app.restoreCard(c, userID, id)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tipo inválido. Use board, column ou card."})
+	}
+}
+
+func trashItemNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Item não encontrado na lixeira"})
+}
+
+func (app *App) restoreBoard(c *fiber.Ctx, userID string, boardID int) error {
+	var ownerID string
+	err := app.db.QueryRow(context.Background(),
+		"SELECT owner_id FROM boards WHERE id = $1 AND deleted_at IS NOT NULL", boardID).Scan(&ownerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return trashItemNotFound(c)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o quadro"})
+	}
+	if ownerID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas o dono pode restaurar o quadro."})
+	}
+	if err := app.boards.RestoreBoard(context.Background(), boardID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return trashItemNotFound(c)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar o quadro"})
+	}
+	board, err := app.boards.GetBoard(context.Background(), boardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o quadro restaurado"})
+	}
+	return c.JSON(board)
+}
+
+func (app *App) restoreColumn(c *fiber.Ctx, userID string, columnID int) error {
+	var boardID int
+	var boardTrashed bool
+	err := app.db.QueryRow(context.Background(), `
+		SELECT col.board_id, b.deleted_at IS NOT NULL FROM columns col
+		JOIN boards b ON b.id = col.board_id
+		WHERE col.id = $1 AND col.deleted_at IS NOT NULL`, columnID).Scan(&boardID, &boardTrashed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return trashItemNotFound(c)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar a coluna"})
+	}
+	if boardTrashed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "O quadro desta coluna está na lixeira. Restaure o quadro primeiro."})
+	}
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+	position, err := restoreAtPosition(context.Background(), tx, "columns", "board_id", "deleted_at IS NULL", boardID, columnID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return trashItemNotFound(c)
+	}
+	if err != nil {
+		log.Printf("Erro ao restaurar a coluna %d: %v", columnID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar a coluna"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a restauração"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil, SenderID: userID})
+	return c.JSON(fiber.Map{"type": "column", "id": columnID, "board_id": boardID, "position": position})
+}
+
+func (app *App) restoreCard(c *fiber.Ctx, userID string, cardID int) error {
+	var boardID, columnID int
+	var columnTrashed, boardTrashed, archived bool
+	err := app.db.QueryRow(context.Background(), `
+		SELECT col.board_id, col.id, col.deleted_at IS NOT NULL, b.deleted_at IS NOT NULL, ca.archived_at IS NOT NULL FROM cards ca
+		JOIN columns col ON col.id = ca.column_id
+		JOIN boards b ON b.id = col.board_id
+		WHERE ca.id = $1 AND ca.deleted_at IS NOT NULL`, cardID).Scan(&boardID, &columnID, &columnTrashed, &boardTrashed, &archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return trashItemNotFound(c)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o card"})
+	}
+	if boardTrashed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "O quadro deste card está na lixeira. Restaure o quadro primeiro."})
+	}
+	if columnTrashed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A coluna deste card está na lixeira. Restaure a coluna primeiro."})
+	}
+	if ok, resp := app.requireBoardCapability(c, userID, boardID, CapEdit); !ok {
+		return resp
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+	}
+	defer tx.Rollback(context.Background())
+	// card arquivado volta para o arquivo, sem ocupar posicao na coluna
+	if archived {
+		_, err = tx.Exec(context.Background(), "UPDATE cards SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", cardID)
+	} else {
+		_, err = restoreAtPosition(context.Background(), tx, "cards", "column_id", "deleted_at IS NULL AND archived_at IS NULL", columnID, cardID)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return trashItemNotFound(c)
+		}
+		log.Printf("Erro ao restaurar o card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar o card"})
+	}
+	if err := app.recordCardActivity(tx, CardActivity{CardID: cardID, BoardID: boardID, ActorID: userID, Action: "restored"}); err != nil {
+		log.Printf("Erro ao registrar restauração do card %d: %v", cardID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar o card"})
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a restauração"})
+	}
+
+	card, err := app.cards.GetCard(context.Background(), cardID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar o card restaurado"})
+	}
+	app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil, SenderID: userID})
+	return c.JSON(card)
+}
+
+// volta o item para a posicao que tinha (ou para o fim, se a lista encolheu) e empurra os vizinhos;
+// live diz quais itens ocupam posicao
+func restoreAtPosition(ctx context.Context, tx pgx.Tx, table, scope, live string, scopeID, id int) (int, error) {
+	var position, count int
+	err := tx.QueryRow(ctx, fmt.Sprintf("SELECT position FROM %s WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", table), id).Scan(&position)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = $1 AND %s", table, scope, live), scopeID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if position > count {
+		position = count
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET position = position + 1 WHERE %s = $1 AND position >= $2 AND %s", table, scope, live), scopeID, position)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET deleted_at = NULL, deleted_by = NULL, position = $2 WHERE id = $1", table), id, position)
+	return position, err
+}
+
+// apagar de vez o que passou da retencao, com os arquivos dos anexos
+func (app *App) purgeTrashBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	rows, err := app.db.Query(ctx, `
+		SELECT a.storage_key FROM card_attachments a
+		JOIN cards ca ON ca.id = a.card_id
+		JOIN columns col ON col.id = ca.column_id
+		JOIN boards b ON b.id = col.board_id
+		WHERE ca.deleted_at < $1 OR col.deleted_at < $1 OR b.deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	var purged int64
+	for _, table := range []string{"cards", "columns", "boards"} {
+		tag, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE deleted_at < $1", cutoff)
+		if err != nil {
+			return 0, err
+		}
+		purged += tag.RowsAffected()
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		if err := app.storage.Delete(ctx, attachmentsBucket, key); err != nil {
+			log.Printf("Aviso: não foi possível remover o anexo %s da lixeira: %v", key, err)
+		}
+	}
+	return purged, nil
+}
+
+// limpeza periodica da lixeira
+func (app *App) purgeTrash(ctx context.Context) {
+	retention := trashRetention()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		purged, err := app.purgeTrashBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Erro ao limpar a lixeira: %v", err)
+		} else if purged > 0 {
+			log.Printf("Itens removidos da lixeira pela retenção: %d", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}